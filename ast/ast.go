@@ -2,6 +2,8 @@ package ast
 
 import (
 	"bytes"
+	"math/big"
+	"reflect"
 	"strings"
 
 	"github.com/geraldywy/monkey/token"
@@ -21,6 +23,21 @@ type Expression interface {
 	expressionNode()
 }
 
+// TokenOf returns the token node was parsed from, or nil if it has none. A statement's
+// token is its first, while e.g. an infix expression's token is its operator.
+func TokenOf(node Node) *token.Token {
+	v := reflect.ValueOf(node)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+	field := v.Elem().FieldByName("Token")
+	if !field.IsValid() || field.Type() != tokenType {
+		return nil
+	}
+
+	return field.Interface().(*token.Token)
+}
+
 type Program struct {
 	Statements []Statement
 }
@@ -34,19 +51,39 @@ func (p *Program) TokenLiteral() string {
 }
 
 func (p *Program) String() string {
-	var sb strings.Builder
+	return statementsString(p.Statements)
+}
 
-	for _, s := range p.Statements {
+// statementsString joins stmts, terminating every expression statement but the last with
+// a ';' so that it is not read as continuing into the next one, e.g. a(b) from a; (b)
+func statementsString(stmts []Statement) string {
+	var sb strings.Builder
+	for i, s := range stmts {
 		sb.WriteString(s.String())
+		if _, ok := s.(*ExpressionStatement); ok && i < len(stmts)-1 {
+			sb.WriteString(";")
+		}
 	}
 
 	return sb.String()
 }
 
+// operandString returns the string of an operand, wrapping arrow functions whose concise
+// body would otherwise extend over the rest of the expression
+func operandString(e Expression) string {
+	if fl, ok := e.(*FunctionLiteral); ok && fl.Arrow {
+		return "(" + fl.String() + ")"
+	}
+
+	return e.String()
+}
+
 type LetStatement struct {
-	Token *token.Token // the token.LET token
-	Name  *Identifier
-	Value Expression
+	Token   *token.Token // the token.LET token
+	Name    *Identifier
+	Pattern Pattern  // set instead of Name for destructuring bindings, e.g. let [a, b] = xs;
+	Type    TypeExpr // the annotated type of Name, if any
+	Value   Expression
 }
 
 func (ls *LetStatement) statementNode()       {}
@@ -80,7 +117,14 @@ func (es *ExpressionStatement) TokenLiteral() string { return es.Token.Literal }
 func (ls *LetStatement) String() string {
 	var out bytes.Buffer
 	out.WriteString(ls.TokenLiteral() + " ")
-	out.WriteString(ls.Name.String())
+	if ls.Pattern != nil {
+		out.WriteString(ls.Pattern.String())
+	} else {
+		out.WriteString(ls.Name.String())
+	}
+	if ls.Type != nil {
+		out.WriteString(": " + ls.Type.String())
+	}
 	out.WriteString(" = ")
 	if ls.Value != nil {
 		out.WriteString(ls.Value.String())
@@ -109,12 +153,43 @@ func (es *ExpressionStatement) String() string {
 type IntegerLiteral struct {
 	Token *token.Token
 	Value int64
+	Big   *big.Int // set instead of Value for literals that do not fit in an int64
 }
 
 func (il *IntegerLiteral) expressionNode()      {}
 func (il *IntegerLiteral) TokenLiteral() string { return il.Token.Literal }
 func (il *IntegerLiteral) String() string       { return il.Token.Literal }
 
+type StringLiteral struct {
+	Token *token.Token
+	Value string
+}
+
+func (sl *StringLiteral) expressionNode()      {}
+func (sl *StringLiteral) TokenLiteral() string { return sl.Token.Literal }
+func (sl *StringLiteral) String() string {
+	var sb strings.Builder
+	sb.WriteByte('"')
+	for i := 0; i < len(sl.Value); i++ {
+		// only the escapes understood by the lexer, other bytes are written as they are
+		switch ch := sl.Value[i]; ch {
+		case '"', '\\':
+			sb.WriteByte('\\')
+			sb.WriteByte(ch)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\t':
+			sb.WriteString(`\t`)
+		case '\r':
+			sb.WriteString(`\r`)
+		default:
+			sb.WriteByte(ch)
+		}
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
+
 type Boolean struct {
 	Token *token.Token
 	Value bool
@@ -124,6 +199,14 @@ func (b *Boolean) expressionNode()      {}
 func (b *Boolean) TokenLiteral() string { return b.Token.Literal }
 func (b *Boolean) String() string       { return b.Token.Literal }
 
+type Null struct {
+	Token *token.Token
+}
+
+func (n *Null) expressionNode()      {}
+func (n *Null) TokenLiteral() string { return n.Token.Literal }
+func (n *Null) String() string       { return n.Token.Literal }
+
 type PrefixExpression struct {
 	Token    *token.Token // The prefix token, e.g. !
 	Operator string
@@ -136,7 +219,7 @@ func (pe *PrefixExpression) String() string {
 	var out bytes.Buffer
 	out.WriteString("(")
 	out.WriteString(pe.Operator)
-	out.WriteString(pe.Right.String())
+	out.WriteString(operandString(pe.Right))
 	out.WriteString(")")
 	return out.String()
 }
@@ -153,9 +236,9 @@ func (oe *InfixExpression) TokenLiteral() string { return oe.Token.Literal }
 func (oe *InfixExpression) String() string {
 	var out bytes.Buffer
 	out.WriteString("(")
-	out.WriteString(oe.Left.String())
+	out.WriteString(operandString(oe.Left))
 	out.WriteString(" " + oe.Operator + " ")
-	out.WriteString(oe.Right.String())
+	out.WriteString(operandString(oe.Right))
 	out.WriteString(")")
 	return out.String()
 }
@@ -163,16 +246,13 @@ func (oe *InfixExpression) String() string {
 type BlockStatement struct {
 	Token      *token.Token // the { token
 	Statements []Statement
+	Rbrace     *token.Token // the } token, nil for a concise body written without braces
 }
 
 func (bs *BlockStatement) statementNode()       {}
 func (bs *BlockStatement) TokenLiteral() string { return bs.Token.Literal }
 func (bs *BlockStatement) String() string {
-	var out bytes.Buffer
-	for _, s := range bs.Statements {
-		out.WriteString(s.String())
-	}
-	return out.String()
+	return "{ " + statementsString(bs.Statements) + " }"
 }
 
 type IfExpression struct {
@@ -186,12 +266,12 @@ func (ie *IfExpression) expressionNode()      {}
 func (ie *IfExpression) TokenLiteral() string { return ie.Token.Literal }
 func (ie *IfExpression) String() string {
 	var out bytes.Buffer
-	out.WriteString("if")
+	out.WriteString("if (")
 	out.WriteString(ie.Condition.String())
-	out.WriteString(" ")
+	out.WriteString(") ")
 	out.WriteString(ie.Consequence.String())
 	if ie.Alternative != nil {
-		out.WriteString("else ")
+		out.WriteString(" else ")
 		out.WriteString(ie.Alternative.String())
 	}
 	return out.String()
@@ -200,22 +280,115 @@ func (ie *IfExpression) String() string {
 type FunctionLiteral struct {
 	Token      *token.Token // The 'fn' token
 	Parameters []*Identifier
+	Defaults   []Expression // parallel to Parameters, nil entries for parameters without a default
+	Rest       *Identifier  // collects the remaining arguments when non nil, e.g. ...rest
+	ParamTypes []TypeExpr   // parallel to Parameters, nil entries for parameters without an annotation
+	ReturnType TypeExpr
 	Body       *BlockStatement
+	Arrow      bool // written in the concise form, e.g. (a, b) => a + b
 }
 
 func (fl *FunctionLiteral) expressionNode()      {}
 func (fl *FunctionLiteral) TokenLiteral() string { return fl.Token.Literal }
 func (fl *FunctionLiteral) String() string {
+	var out bytes.Buffer
+	if fl.Arrow {
+		out.WriteString(fl.Signature())
+		out.WriteString(" => ")
+		// a concise body is a single expression, not wrapped in braces
+		if fl.Body.Token.Type != token.LBRACE && len(fl.Body.Statements) == 1 {
+			out.WriteString(fl.Body.Statements[0].String())
+		} else {
+			out.WriteString(fl.Body.String())
+		}
+		return out.String()
+	}
+	out.WriteString(fl.TokenLiteral())
+	out.WriteString(fl.Signature())
+	out.WriteString(" ")
+	out.WriteString(fl.Body.String())
+	return out.String()
+}
+
+// Default returns the default value of the i-th parameter, or nil if it has none.
+func (fl *FunctionLiteral) Default(i int) Expression {
+	if i >= len(fl.Defaults) {
+		return nil
+	}
+
+	return fl.Defaults[i]
+}
+
+// ParamType returns the annotated type of the i-th parameter, or nil if it has none.
+func (fl *FunctionLiteral) ParamType(i int) TypeExpr {
+	if i >= len(fl.ParamTypes) {
+		return nil
+	}
+
+	return fl.ParamTypes[i]
+}
+
+// Arity returns the minimum and maximum number of arguments accepted, max is -1 for variadic functions.
+func (fl *FunctionLiteral) Arity() (int, int) {
+	min := 0
+	for i := range fl.Parameters {
+		if fl.Default(i) == nil {
+			min++
+		}
+	}
+	if fl.Rest != nil {
+		return min, -1
+	}
+
+	return min, len(fl.Parameters)
+}
+
+// Signature returns the parameter list and return type as written in source, e.g.
+// (a: int, b = 10, ...rest) -> int
+func (fl *FunctionLiteral) Signature() string {
 	var out bytes.Buffer
 	params := []string{}
-	for _, p := range fl.Parameters {
+	for i, p := range fl.Parameters {
+		param := p.String()
+		if t := fl.ParamType(i); t != nil {
+			param += ": " + t.String()
+		}
+		if def := fl.Default(i); def != nil {
+			param += " = " + def.String()
+		}
+		params = append(params, param)
+	}
+	if fl.Rest != nil {
+		params = append(params, "..."+fl.Rest.String())
+	}
+	out.WriteString("(")
+	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(")")
+	if fl.ReturnType != nil {
+		out.WriteString(" -> " + fl.ReturnType.String())
+	}
+	return out.String()
+}
+
+type MacroLiteral struct {
+	Token      *token.Token // The 'macro' token
+	Parameters []*Identifier
+	Body       *BlockStatement
+}
+
+func (ml *MacroLiteral) expressionNode()      {}
+func (ml *MacroLiteral) TokenLiteral() string { return ml.Token.Literal }
+func (ml *MacroLiteral) String() string {
+	var out bytes.Buffer
+	params := []string{}
+	for _, p := range ml.Parameters {
 		params = append(params, p.String())
 	}
-	out.WriteString(fl.TokenLiteral())
+	out.WriteString(ml.TokenLiteral())
 	out.WriteString("(")
 	out.WriteString(strings.Join(params, ", "))
 	out.WriteString(") ")
-	out.WriteString(fl.Body.String())
+	out.WriteString(ml.Body.String())
 	return out.String()
 }
 
@@ -233,9 +406,152 @@ func (ce *CallExpression) String() string {
 	for _, a := range ce.Arguments {
 		args = append(args, a.String())
 	}
-	out.WriteString(ce.Function.String())
+	out.WriteString(operandString(ce.Function))
 	out.WriteString("(")
 	out.WriteString(strings.Join(args, ", "))
 	out.WriteString(")")
 	return out.String()
 }
+
+type SpreadExpression struct {
+	Token *token.Token // the '...' token
+	Value Expression
+}
+
+func (se *SpreadExpression) expressionNode()      {}
+func (se *SpreadExpression) TokenLiteral() string { return se.Token.Literal }
+func (se *SpreadExpression) String() string       { return "..." + se.Value.String() }
+
+type NamedArgument struct {
+	Token *token.Token // the token.IDENT token of the name
+	Name  *Identifier
+	Value Expression
+}
+
+func (na *NamedArgument) expressionNode()      {}
+func (na *NamedArgument) TokenLiteral() string { return na.Token.Literal }
+func (na *NamedArgument) String() string       { return na.Name.String() + ": " + na.Value.String() }
+
+type MemberExpression struct {
+	Token    *token.Token // the '.' or '?.' token
+	Object   Expression
+	Property *Identifier
+	Optional bool // ?. short-circuits to null when Object is null
+}
+
+func (me *MemberExpression) expressionNode()      {}
+func (me *MemberExpression) TokenLiteral() string { return me.Token.Literal }
+func (me *MemberExpression) String() string {
+	var out bytes.Buffer
+	out.WriteString("(")
+	out.WriteString(operandString(me.Object))
+	out.WriteString(me.Token.Literal)
+	out.WriteString(me.Property.String())
+	out.WriteString(")")
+	return out.String()
+}
+
+type IndexExpression struct {
+	Token    *token.Token // the '[' or '?[' token
+	Left     Expression
+	Index    Expression
+	Optional bool // ?[ short-circuits to null when Left is null
+}
+
+func (ie *IndexExpression) expressionNode()      {}
+func (ie *IndexExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *IndexExpression) String() string {
+	var out bytes.Buffer
+	out.WriteString("(")
+	out.WriteString(operandString(ie.Left))
+	out.WriteString(ie.Token.Literal)
+	out.WriteString(ie.Index.String())
+	out.WriteString("])")
+	return out.String()
+}
+
+type ThrowStatement struct {
+	Token *token.Token // the 'throw' token
+	Value Expression
+}
+
+func (ts *ThrowStatement) statementNode()       {}
+func (ts *ThrowStatement) TokenLiteral() string { return ts.Token.Literal }
+func (ts *ThrowStatement) String() string {
+	var out bytes.Buffer
+	out.WriteString(ts.TokenLiteral() + " ")
+	out.WriteString(ts.Value.String())
+	out.WriteString(";")
+	return out.String()
+}
+
+type TryExpression struct {
+	Token      *token.Token // the 'try' token
+	Block      *BlockStatement
+	CatchParam *Identifier // binds the caught value, nil when there is no catch clause
+	Catch      *BlockStatement
+	Finally    *BlockStatement
+}
+
+func (te *TryExpression) expressionNode()      {}
+func (te *TryExpression) TokenLiteral() string { return te.Token.Literal }
+func (te *TryExpression) String() string {
+	var out bytes.Buffer
+	out.WriteString("try ")
+	out.WriteString(te.Block.String())
+	if te.Catch != nil {
+		out.WriteString(" catch (")
+		out.WriteString(te.CatchParam.String())
+		out.WriteString(") ")
+		out.WriteString(te.Catch.String())
+	}
+	if te.Finally != nil {
+		out.WriteString(" finally ")
+		out.WriteString(te.Finally.String())
+	}
+	return out.String()
+}
+
+type DeferStatement struct {
+	Token *token.Token // the 'defer' token
+	Call  *CallExpression
+}
+
+func (ds *DeferStatement) statementNode()       {}
+func (ds *DeferStatement) TokenLiteral() string { return ds.Token.Literal }
+func (ds *DeferStatement) String() string {
+	var out bytes.Buffer
+	out.WriteString(ds.TokenLiteral() + " ")
+	out.WriteString(ds.Call.String())
+	out.WriteString(";")
+	return out.String()
+}
+
+type ImportStatement struct {
+	Token *token.Token // the 'import' token
+	Path  *StringLiteral
+	Alias *Identifier // the namespace the module's exports are accessed through
+}
+
+func (is *ImportStatement) statementNode()       {}
+func (is *ImportStatement) TokenLiteral() string { return is.Token.Literal }
+func (is *ImportStatement) String() string {
+	var out bytes.Buffer
+	out.WriteString(is.TokenLiteral() + " ")
+	out.WriteString(is.Path.String())
+	out.WriteString(" as ")
+	out.WriteString(is.Alias.String())
+	out.WriteString(";")
+	return out.String()
+}
+
+type ExportStatement struct {
+	Token     *token.Token // the 'export' token
+	Statement *LetStatement
+}
+
+func (es *ExportStatement) statementNode()       {}
+func (es *ExportStatement) TokenLiteral() string { return es.Token.Literal }
+func (es *ExportStatement) String() string {
+	return es.TokenLiteral() + " " + es.Statement.String()
+}
@@ -1,6 +1,7 @@
 package ast
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/geraldywy/monkey/token"
@@ -25,3 +26,55 @@ func TestString(t *testing.T) {
 		t.Errorf("program.String() wrong. got=%q", program.String())
 	}
 }
+
+func TestBindArguments(t *testing.T) {
+	sig := &CallSignature{
+		Name:     "connect",
+		Params:   []string{"host", "port", "timeout"},
+		Required: 2,
+	}
+	tests := []struct {
+		positional    []int
+		named         []NamedValue[int]
+		wantBound     []int
+		wantSupplied  []bool
+		wantErrSubstr string
+	}{
+		{[]int{1, 2}, nil, []int{1, 2, 0}, []bool{true, true, false}, ""},
+		{nil, []NamedValue[int]{{"port", 2}, {"host", 1}}, []int{1, 2, 0}, []bool{true, true, false}, ""},
+		{[]int{1}, []NamedValue[int]{{"timeout", 3}, {"port", 2}}, []int{1, 2, 3}, []bool{true, true, true}, ""},
+		{[]int{1, 2, 3, 4}, nil, nil, nil, "too many arguments in call to connect(host, port, [timeout])"},
+		{[]int{1}, []NamedValue[int]{{"user", 2}}, nil, nil, "unknown argument user"},
+		{[]int{1}, []NamedValue[int]{{"host", 2}}, nil, nil, "argument host given more than once"},
+		{[]int{1}, nil, nil, nil, "missing argument port"},
+	}
+	for i, tt := range tests {
+		bound, supplied, _, err := BindArguments(sig, tt.positional, tt.named)
+		if tt.wantErrSubstr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErrSubstr) {
+				t.Errorf("tests[%d] - expected error containing %q, got=%v", i, tt.wantErrSubstr, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("tests[%d] - unexpected error: %s", i, err)
+		}
+		for j := range tt.wantBound {
+			if bound[j] != tt.wantBound[j] || supplied[j] != tt.wantSupplied[j] {
+				t.Errorf("tests[%d] - slot %d wrong. want=(%d, %t), got=(%d, %t)",
+					i, j, tt.wantBound[j], tt.wantSupplied[j], bound[j], supplied[j])
+			}
+		}
+	}
+}
+
+func TestBindArgumentsVariadic(t *testing.T) {
+	sig := &CallSignature{Name: "log", Params: []string{"level"}, Required: 1, Variadic: true}
+	_, _, rest, err := BindArguments(sig, []int{1, 2, 3}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(rest) != 2 || rest[0] != 2 || rest[1] != 3 {
+		t.Errorf("rest wrong. got=%v", rest)
+	}
+}
@@ -0,0 +1,116 @@
+package ast
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// CallSignature describes the parameters a callable accepts. FunctionLiteral derives one
+// from its parameter list, Go builtins may declare one to accept named arguments.
+type CallSignature struct {
+	Name     string
+	Params   []string
+	Required int // the leading Required params have no default and must be supplied
+	Variadic bool
+}
+
+func (cs *CallSignature) String() string {
+	params := []string{}
+	for i, p := range cs.Params {
+		if i >= cs.Required {
+			p = "[" + p + "]"
+		}
+		params = append(params, p)
+	}
+	if cs.Variadic {
+		params = append(params, "...")
+	}
+
+	return cs.Name + "(" + strings.Join(params, ", ") + ")"
+}
+
+// CallSignature returns the signature of the function, name is the binding it is called through.
+func (fl *FunctionLiteral) CallSignature(name string) *CallSignature {
+	sig := &CallSignature{
+		Name:     name,
+		Params:   make([]string, 0, len(fl.Parameters)),
+		Variadic: fl.Rest != nil,
+	}
+	for i, p := range fl.Parameters {
+		sig.Params = append(sig.Params, p.Value)
+		if fl.Default(i) == nil {
+			sig.Required = i + 1
+		}
+	}
+
+	return sig
+}
+
+type NamedValue[T any] struct {
+	Name  string
+	Value T
+}
+
+// BindArguments matches already evaluated positional and named arguments against sig.
+// bound holds one slot per parameter and supplied reports which of them were filled, the
+// remaining slots fall back to their defaults. rest holds the surplus positional arguments
+// of a variadic callable.
+func BindArguments[T any](sig *CallSignature, positional []T, named []NamedValue[T]) (bound []T, supplied []bool, rest []T, err error) {
+	bound = make([]T, len(sig.Params))
+	supplied = make([]bool, len(sig.Params))
+
+	for i, arg := range positional {
+		if i >= len(sig.Params) {
+			if !sig.Variadic {
+				return nil, nil, nil, errors.New(fmt.Sprintf(
+					"too many arguments in call to %s, got %d",
+					sig,
+					len(positional),
+				))
+			}
+			rest = append(rest, arg)
+			continue
+		}
+		bound[i] = arg
+		supplied[i] = true
+	}
+
+	for _, arg := range named {
+		idx := -1
+		for i, p := range sig.Params {
+			if p == arg.Name {
+				idx = i
+				break
+			}
+		}
+		if idx == -1 {
+			return nil, nil, nil, errors.New(fmt.Sprintf(
+				"unknown argument %s in call to %s",
+				arg.Name,
+				sig,
+			))
+		}
+		if supplied[idx] {
+			return nil, nil, nil, errors.New(fmt.Sprintf(
+				"argument %s given more than once in call to %s",
+				arg.Name,
+				sig,
+			))
+		}
+		bound[idx] = arg.Value
+		supplied[idx] = true
+	}
+
+	for i := 0; i < sig.Required; i++ {
+		if !supplied[i] {
+			return nil, nil, nil, errors.New(fmt.Sprintf(
+				"missing argument %s in call to %s",
+				sig.Params[i],
+				sig,
+			))
+		}
+	}
+
+	return bound, supplied, rest, nil
+}
@@ -0,0 +1,42 @@
+package ast_test
+
+import (
+	"testing"
+
+	"github.com/geraldywy/monkey/ast"
+)
+
+func TestEqual(t *testing.T) {
+	tests := []struct {
+		a, b  string
+		equal bool
+	}{
+		{"1 + 2 * 3", "1 + (2 * 3)", true},
+		{"let x = fn(a) { a };", "let x =\n  fn(a) {\n    a;\n  };", true},
+		{"007", "7", true},
+		{"123456789012345678901234567890", "(123456789012345678901234567890)", true},
+		{"x => x", "(x) => x", true},
+		{"1 + 2 * 3", "(1 + 2) * 3", false},
+		{"a - b", "a + b", false},
+		{"a.b", "a?.b", false},
+		{"x => x", "fn(x) { x }", false},
+		{"fn(a) { a }", "fn(a, ...b) { a }", false},
+		{"f(a); g(b)", "f(a)", false},
+		{"123456789012345678901234567890", "123456789012345678901234567891", false},
+		{"if (x) { 1 }", "if (x) { 1 } else { 2 }", false},
+	}
+	for _, tt := range tests {
+		if got := ast.Equal(parse(t, tt.a), parse(t, tt.b)); got != tt.equal {
+			t.Errorf("Equal(%q, %q) wrong. want=%t, got=%t", tt.a, tt.b, tt.equal, got)
+		}
+	}
+}
+
+func TestStringRoundTrip(t *testing.T) {
+	program := parse(t, jsonCorpus)
+	reparsed := parse(t, program.String())
+	if !ast.Equal(program, reparsed) {
+		t.Errorf("program does not round trip through String().\nstring=%q\nreparsed=%q",
+			program.String(), reparsed.String())
+	}
+}
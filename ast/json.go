@@ -0,0 +1,318 @@
+package ast
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/geraldywy/monkey/token"
+)
+
+// nodeKinds maps the "kind" tag of an encoded node to its type
+var nodeKinds = map[string]reflect.Type{}
+
+func init() {
+	for _, n := range []Node{
+		&Program{},
+		&LetStatement{},
+		&ReturnStatement{},
+		&ExpressionStatement{},
+		&BlockStatement{},
+		&ThrowStatement{},
+		&DeferStatement{},
+		&ImportStatement{},
+		&ExportStatement{},
+		&Identifier{},
+		&IntegerLiteral{},
+		&StringLiteral{},
+		&Boolean{},
+		&Null{},
+		&PrefixExpression{},
+		&InfixExpression{},
+		&IfExpression{},
+		&FunctionLiteral{},
+		&MacroLiteral{},
+		&CallExpression{},
+		&SpreadExpression{},
+		&NamedArgument{},
+		&MemberExpression{},
+		&IndexExpression{},
+		&MatchExpression{},
+		&MatchArm{},
+		&TryExpression{},
+		&WildcardPattern{},
+		&IdentifierPattern{},
+		&LiteralPattern{},
+		&ArrayPattern{},
+		&HashPattern{},
+		&NamedType{},
+		&FunctionType{},
+	} {
+		t := reflect.TypeOf(n).Elem()
+		nodeKinds[t.Name()] = t
+	}
+}
+
+var (
+	tokenType = reflect.TypeOf(&token.Token{})
+	nodeType  = reflect.TypeOf((*Node)(nil)).Elem()
+)
+
+type jsonPos struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+type jsonToken struct {
+	Type    token.TokenType `json:"type"`
+	Literal string          `json:"literal"`
+}
+
+// EncodeJSON encodes node and all of its children. Every node is an object tagged with
+// its "kind" and, when it has a token, the "pos" the token starts at. The encoding is
+// lossless, DecodeJSON rebuilds an identical tree from it.
+func EncodeJSON(node Node) ([]byte, error) {
+	v, err := encodeValue(reflect.ValueOf(node))
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(v)
+}
+
+// DecodeJSON decodes a tree produced by EncodeJSON
+func DecodeJSON(data []byte) (Node, error) {
+	v := reflect.New(nodeType)
+	if err := decodeValue(json.RawMessage(data), v.Elem()); err != nil {
+		return nil, err
+	}
+
+	return v.Elem().Interface().(Node), nil
+}
+
+func encodeValue(v reflect.Value) (interface{}, error) {
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.IsNil() {
+			return nil, nil
+		}
+		return encodeValue(v.Elem())
+	case reflect.Slice:
+		if v.IsNil() {
+			return nil, nil
+		}
+		elems := make([]interface{}, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			e, err := encodeValue(v.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			elems = append(elems, e)
+		}
+		return elems, nil
+	case reflect.Ptr:
+		if v.IsNil() {
+			return nil, nil
+		}
+		if v.Type().Implements(reflect.TypeOf((*json.Marshaler)(nil)).Elem()) {
+			return v.Interface(), nil
+		}
+		if v.Type().Elem().Kind() == reflect.Struct {
+			return encodeStruct(v.Elem())
+		}
+	}
+
+	return v.Interface(), nil
+}
+
+func encodeStruct(v reflect.Value) (map[string]interface{}, error) {
+	obj := make(map[string]interface{})
+	if _, ok := v.Addr().Interface().(Node); ok {
+		if _, ok := nodeKinds[v.Type().Name()]; !ok {
+			return nil, errors.New(fmt.Sprintf("cannot encode unknown node type %s", v.Type()))
+		}
+		obj["kind"] = v.Type().Name()
+	}
+
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if field.Type == tokenType {
+			tkn, _ := v.Field(i).Interface().(*token.Token)
+			if field.Name != "Token" {
+				// any other token, e.g. a closing brace, is an object of its own
+				obj[jsonName(field.Name)] = encodeToken(tkn, make(map[string]interface{}))
+				continue
+			}
+			encodeToken(tkn, obj)
+			continue
+		}
+		fv, err := encodeValue(v.Field(i))
+		if err != nil {
+			return nil, err
+		}
+		obj[jsonName(field.Name)] = fv
+	}
+
+	return obj, nil
+}
+
+func decodeValue(raw json.RawMessage, v reflect.Value) error {
+	if string(raw) == "null" {
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+
+	switch {
+	case v.Kind() == reflect.Interface:
+		node, err := decodeNode(raw)
+		if err != nil {
+			return err
+		}
+		nv := reflect.ValueOf(node)
+		if !nv.Type().AssignableTo(v.Type()) {
+			return errors.New(fmt.Sprintf("%s cannot be used as %s", nv.Elem().Type().Name(), v.Type().Name()))
+		}
+		v.Set(nv)
+		return nil
+	case v.Kind() == reflect.Slice:
+		var elems []json.RawMessage
+		if err := json.Unmarshal(raw, &elems); err != nil {
+			return err
+		}
+		s := reflect.MakeSlice(v.Type(), len(elems), len(elems))
+		for i, e := range elems {
+			if err := decodeValue(e, s.Index(i)); err != nil {
+				return err
+			}
+		}
+		v.Set(s)
+		return nil
+	case v.Kind() == reflect.Ptr && v.Type().Implements(nodeType):
+		node, err := decodeNode(raw)
+		if err != nil {
+			return err
+		}
+		nv := reflect.ValueOf(node)
+		if nv.Type() != v.Type() {
+			return errors.New(fmt.Sprintf("%s cannot be used as %s", nv.Elem().Type().Name(), v.Type().Elem().Name()))
+		}
+		v.Set(nv)
+		return nil
+	case v.Kind() == reflect.Ptr && v.Type().Elem().Kind() == reflect.Struct &&
+		!v.Type().Implements(reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()):
+		s := reflect.New(v.Type().Elem())
+		if err := decodeStruct(raw, s.Elem()); err != nil {
+			return err
+		}
+		// the key of a shorthand pair is the identifier its value binds, as when parsed
+		if pair, ok := s.Interface().(*HashPatternPair); ok && pair.Shorthand {
+			if ip, ok := pair.Value.(*IdentifierPattern); ok {
+				pair.Key = ip.Name
+			}
+		}
+		v.Set(s)
+		return nil
+	}
+
+	return json.Unmarshal(raw, v.Addr().Interface())
+}
+
+func decodeNode(raw json.RawMessage) (Node, error) {
+	var tag struct {
+		Kind string `json:"kind"`
+	}
+	if err := json.Unmarshal(raw, &tag); err != nil {
+		return nil, err
+	}
+	t, ok := nodeKinds[tag.Kind]
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("unknown node kind %q", tag.Kind))
+	}
+	v := reflect.New(t)
+	if err := decodeStruct(raw, v.Elem()); err != nil {
+		return nil, err
+	}
+
+	return v.Interface().(Node), nil
+}
+
+// encodeToken sets the "token" and "pos" of obj to those of tkn, returning obj
+func encodeToken(tkn *token.Token, obj map[string]interface{}) map[string]interface{} {
+	if tkn == nil {
+		obj["token"] = nil
+		return obj
+	}
+	obj["token"] = jsonToken{Type: tkn.Type, Literal: tkn.Literal}
+	obj["pos"] = jsonPos{Line: tkn.Line, Column: tkn.Column}
+
+	return obj
+}
+
+func decodeStruct(raw json.RawMessage, v reflect.Value) error {
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &obj); err != nil {
+		return err
+	}
+
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if field.Type == tokenType {
+			tokenObj := obj
+			if field.Name != "Token" {
+				tokenObj = nil
+				if tokenRaw, ok := obj[jsonName(field.Name)]; ok {
+					if err := json.Unmarshal(tokenRaw, &tokenObj); err != nil {
+						return errors.New(fmt.Sprintf("%s.%s: %s", v.Type().Name(), field.Name, err))
+					}
+				}
+			}
+			if err := decodeToken(tokenObj, v.Field(i)); err != nil {
+				return err
+			}
+			continue
+		}
+		fieldRaw, ok := obj[jsonName(field.Name)]
+		if !ok {
+			continue
+		}
+		if err := decodeValue(fieldRaw, v.Field(i)); err != nil {
+			return errors.New(fmt.Sprintf("%s.%s: %s", v.Type().Name(), field.Name, err))
+		}
+	}
+
+	return nil
+}
+
+func decodeToken(obj map[string]json.RawMessage, v reflect.Value) error {
+	raw, ok := obj["token"]
+	if !ok || string(raw) == "null" {
+		return nil
+	}
+	var jt jsonToken
+	if err := json.Unmarshal(raw, &jt); err != nil {
+		return err
+	}
+	var pos jsonPos
+	if posRaw, ok := obj["pos"]; ok {
+		if err := json.Unmarshal(posRaw, &pos); err != nil {
+			return err
+		}
+	}
+	v.Set(reflect.ValueOf(&token.Token{
+		Type:    jt.Type,
+		Literal: jt.Literal,
+		Line:    pos.Line,
+		Column:  pos.Column,
+	}))
+
+	return nil
+}
+
+// jsonName lower cases the first letter of a field name, e.g. ReturnValue becomes returnValue
+func jsonName(field string) string {
+	r, size := utf8.DecodeRuneInString(field)
+	return string(unicode.ToLower(r)) + field[size:]
+}
@@ -0,0 +1,133 @@
+package ast_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/geraldywy/monkey/ast"
+)
+
+const jsonCorpus = `import "lib/math.mk" as m;
+export let add = fn(a, b = 10, ...rest) { a + b };
+let [x, _, ...xs] = list;
+let {name, age: years} = person;
+let big = 123456789012345678901234567890;
+let s = "quoted \"string\"\n";
+let arrow = (a) => a ** 2 ** 3;
+let f = fn() { defer close(h); throw err; return null; };
+let unless = macro(c, a) { quote(if (!(unquote(c))) { unquote(a) }) };
+if (x < y) { x } else { y };
+match (v) { 0 => zero, -1 => neg, [h, ...t] if h > 0 => { h }, {k: true} => k, _ => 0 };
+try { f(1, ...xs, named: 2) } catch (e) { e?.message ?? m.sqrt(~x % 3) } finally { xs?[0][1] };
+`
+
+func TestJSONRoundTrip(t *testing.T) {
+	program := parse(t, jsonCorpus)
+
+	encoded, err := ast.EncodeJSON(program)
+	if err != nil {
+		t.Fatalf("unexpected encode error: %s", err)
+	}
+	decoded, err := ast.DecodeJSON(encoded)
+	if err != nil {
+		t.Fatalf("unexpected decode error: %s", err)
+	}
+	reencoded, err := ast.EncodeJSON(decoded)
+	if err != nil {
+		t.Fatalf("unexpected encode error: %s", err)
+	}
+	if string(encoded) != string(reencoded) {
+		t.Errorf("re-encoded JSON differs.\nwant=%s\ngot=%s", encoded, reencoded)
+	}
+	if !ast.Equal(decoded, program) {
+		t.Errorf("decoded program differs. want=%q, got=%q", program.String(), decoded.String())
+	}
+}
+
+func TestJSONKeepsShorthand(t *testing.T) {
+	encoded, err := ast.EncodeJSON(parse(t, "let {a, b: d} = c;"))
+	if err != nil {
+		t.Fatalf("unexpected encode error: %s", err)
+	}
+	decoded, err := ast.DecodeJSON(encoded)
+	if err != nil {
+		t.Fatalf("unexpected decode error: %s", err)
+	}
+
+	visits := make(map[*ast.Identifier]int)
+	ast.Inspect(decoded, func(n ast.Node) bool {
+		if ident, ok := n.(*ast.Identifier); ok {
+			visits[ident]++
+		}
+		return true
+	})
+	pair := decoded.(*ast.Program).Statements[0].(*ast.LetStatement).Pattern.(*ast.HashPattern).Pairs[0]
+	if !pair.Shorthand || pair.Key != pair.Value.(*ast.IdentifierPattern).Name {
+		t.Fatalf("decoded pair is not shorthand. got=%+v", pair)
+	}
+	if visits[pair.Key] != 1 {
+		t.Errorf("shorthand key visited wrong number of times. want=1, got=%d", visits[pair.Key])
+	}
+	if len(visits) != 4 {
+		t.Errorf("wrong number of identifiers visited. want=4, got=%d", len(visits))
+	}
+}
+
+func TestJSONKindAndPosition(t *testing.T) {
+	encoded, err := ast.EncodeJSON(parse(t, "let x =\n  5;"))
+	if err != nil {
+		t.Fatalf("unexpected encode error: %s", err)
+	}
+
+	type pos struct {
+		Line   int `json:"line"`
+		Column int `json:"column"`
+	}
+	var prog struct {
+		Kind       string `json:"kind"`
+		Statements []struct {
+			Kind string `json:"kind"`
+			Pos  pos    `json:"pos"`
+			Name struct {
+				Kind  string `json:"kind"`
+				Pos   pos    `json:"pos"`
+				Value string `json:"value"`
+			} `json:"name"`
+			Value struct {
+				Kind  string `json:"kind"`
+				Pos   pos    `json:"pos"`
+				Value int64  `json:"value"`
+			} `json:"value"`
+		} `json:"statements"`
+	}
+	if err := json.Unmarshal(encoded, &prog); err != nil {
+		t.Fatalf("unexpected unmarshal error: %s", err)
+	}
+	if prog.Kind != "Program" || len(prog.Statements) != 1 {
+		t.Fatalf("program wrong. got=%s", encoded)
+	}
+	let := prog.Statements[0]
+	if let.Kind != "LetStatement" || let.Pos != (pos{1, 1}) {
+		t.Errorf("let statement wrong. got kind=%s pos=%v", let.Kind, let.Pos)
+	}
+	if let.Name.Kind != "Identifier" || let.Name.Value != "x" || let.Name.Pos != (pos{1, 5}) {
+		t.Errorf("let name wrong. got kind=%s value=%s pos=%v", let.Name.Kind, let.Name.Value, let.Name.Pos)
+	}
+	if let.Value.Kind != "IntegerLiteral" || let.Value.Value != 5 || let.Value.Pos != (pos{2, 3}) {
+		t.Errorf("let value wrong. got kind=%s value=%d pos=%v", let.Value.Kind, let.Value.Value, let.Value.Pos)
+	}
+}
+
+func TestJSONDecodeErrors(t *testing.T) {
+	tests := []string{
+		`{"kind": "Spaceship"}`,
+		`{"kind": "ExpressionStatement", "expression": {"kind": "LetStatement"}}`,
+		`{"kind": "IfExpression", "consequence": {"kind": "Identifier"}}`,
+		`[1, 2]`,
+	}
+	for _, input := range tests {
+		if _, err := ast.DecodeJSON([]byte(input)); err == nil {
+			t.Errorf("expected decode error for %s, got none", input)
+		}
+	}
+}
@@ -0,0 +1,301 @@
+package ast
+
+type ModifierFunc func(Node) Node
+
+// Modify rebuilds the tree rooted at node bottom up, replacing every node with the
+// result of calling modifier on it. The original tree is left untouched. A child
+// replaced by a node of a type its parent cannot hold is left as it was.
+func Modify(node Node, modifier ModifierFunc) Node {
+	switch node := node.(type) {
+	case *Program:
+		cp := *node
+		cp.Statements = modifyStatements(node.Statements, modifier)
+		return modifier(&cp)
+	case *ExpressionStatement:
+		cp := *node
+		cp.Expression = modifyExpression(node.Expression, modifier)
+		return modifier(&cp)
+	case *LetStatement:
+		cp := *node
+		cp.Name = modifyIdentifier(node.Name, modifier)
+		cp.Pattern = modifyPattern(node.Pattern, modifier)
+		cp.Type = modifyType(node.Type, modifier)
+		cp.Value = modifyExpression(node.Value, modifier)
+		return modifier(&cp)
+	case *ReturnStatement:
+		cp := *node
+		cp.ReturnValue = modifyExpression(node.ReturnValue, modifier)
+		return modifier(&cp)
+	case *ThrowStatement:
+		cp := *node
+		cp.Value = modifyExpression(node.Value, modifier)
+		return modifier(&cp)
+	case *DeferStatement:
+		cp := *node
+		if call, ok := Modify(node.Call, modifier).(*CallExpression); ok {
+			cp.Call = call
+		}
+		return modifier(&cp)
+	case *ImportStatement:
+		cp := *node
+		if path, ok := Modify(node.Path, modifier).(*StringLiteral); ok {
+			cp.Path = path
+		}
+		cp.Alias = modifyIdentifier(node.Alias, modifier)
+		return modifier(&cp)
+	case *ExportStatement:
+		cp := *node
+		if let, ok := Modify(node.Statement, modifier).(*LetStatement); ok {
+			cp.Statement = let
+		}
+		return modifier(&cp)
+	case *BlockStatement:
+		cp := *node
+		cp.Statements = modifyStatements(node.Statements, modifier)
+		return modifier(&cp)
+	case *PrefixExpression:
+		cp := *node
+		cp.Right = modifyExpression(node.Right, modifier)
+		return modifier(&cp)
+	case *InfixExpression:
+		cp := *node
+		cp.Left = modifyExpression(node.Left, modifier)
+		cp.Right = modifyExpression(node.Right, modifier)
+		return modifier(&cp)
+	case *IfExpression:
+		cp := *node
+		cp.Condition = modifyExpression(node.Condition, modifier)
+		cp.Consequence = modifyBlock(node.Consequence, modifier)
+		cp.Alternative = modifyBlock(node.Alternative, modifier)
+		return modifier(&cp)
+	case *FunctionLiteral:
+		cp := *node
+		cp.Parameters = modifyIdentifiers(node.Parameters, modifier)
+		cp.ParamTypes = modifyTypes(node.ParamTypes, modifier)
+		cp.Defaults = modifyExpressions(node.Defaults, modifier)
+		cp.Rest = modifyIdentifier(node.Rest, modifier)
+		cp.ReturnType = modifyType(node.ReturnType, modifier)
+		cp.Body = modifyBlock(node.Body, modifier)
+		return modifier(&cp)
+	case *MacroLiteral:
+		cp := *node
+		cp.Parameters = modifyIdentifiers(node.Parameters, modifier)
+		cp.Body = modifyBlock(node.Body, modifier)
+		return modifier(&cp)
+	case *CallExpression:
+		cp := *node
+		cp.Function = modifyExpression(node.Function, modifier)
+		cp.Arguments = modifyExpressions(node.Arguments, modifier)
+		return modifier(&cp)
+	case *SpreadExpression:
+		cp := *node
+		cp.Value = modifyExpression(node.Value, modifier)
+		return modifier(&cp)
+	case *NamedArgument:
+		cp := *node
+		cp.Name = modifyIdentifier(node.Name, modifier)
+		cp.Value = modifyExpression(node.Value, modifier)
+		return modifier(&cp)
+	case *MemberExpression:
+		cp := *node
+		cp.Object = modifyExpression(node.Object, modifier)
+		cp.Property = modifyIdentifier(node.Property, modifier)
+		return modifier(&cp)
+	case *IndexExpression:
+		cp := *node
+		cp.Left = modifyExpression(node.Left, modifier)
+		cp.Index = modifyExpression(node.Index, modifier)
+		return modifier(&cp)
+	case *MatchExpression:
+		cp := *node
+		cp.Subject = modifyExpression(node.Subject, modifier)
+		cp.Arms = make([]*MatchArm, 0, len(node.Arms))
+		for _, arm := range node.Arms {
+			if ma, ok := Modify(arm, modifier).(*MatchArm); ok {
+				cp.Arms = append(cp.Arms, ma)
+				continue
+			}
+			cp.Arms = append(cp.Arms, arm)
+		}
+		return modifier(&cp)
+	case *MatchArm:
+		cp := *node
+		cp.Pattern = modifyPattern(node.Pattern, modifier)
+		cp.Guard = modifyExpression(node.Guard, modifier)
+		cp.Body = modifyBlock(node.Body, modifier)
+		return modifier(&cp)
+	case *TryExpression:
+		cp := *node
+		cp.Block = modifyBlock(node.Block, modifier)
+		cp.CatchParam = modifyIdentifier(node.CatchParam, modifier)
+		cp.Catch = modifyBlock(node.Catch, modifier)
+		cp.Finally = modifyBlock(node.Finally, modifier)
+		return modifier(&cp)
+	case *IdentifierPattern:
+		cp := *node
+		cp.Name = modifyIdentifier(node.Name, modifier)
+		return modifier(&cp)
+	case *LiteralPattern:
+		cp := *node
+		cp.Value = modifyExpression(node.Value, modifier)
+		return modifier(&cp)
+	case *ArrayPattern:
+		cp := *node
+		cp.Elements = make([]Pattern, 0, len(node.Elements))
+		for _, e := range node.Elements {
+			cp.Elements = append(cp.Elements, modifyPattern(e, modifier))
+		}
+		cp.Rest = modifyIdentifier(node.Rest, modifier)
+		return modifier(&cp)
+	case *HashPattern:
+		cp := *node
+		cp.Pairs = make([]*HashPatternPair, 0, len(node.Pairs))
+		for _, pair := range node.Pairs {
+			modified := &HashPatternPair{Value: modifyPattern(pair.Value, modifier), Shorthand: pair.Shorthand}
+			// a shorthand pair keeps sharing its key with the identifier it binds
+			if ip, ok := modified.Value.(*IdentifierPattern); ok && pair.Shorthand {
+				modified.Key = ip.Name
+			} else {
+				modified.Key = modifyIdentifier(pair.Key, modifier)
+			}
+			cp.Pairs = append(cp.Pairs, modified)
+		}
+		return modifier(&cp)
+	case *NamedType:
+		cp := *node
+		return modifier(&cp)
+	case *FunctionType:
+		cp := *node
+		cp.Parameters = modifyTypes(node.Parameters, modifier)
+		cp.Return = modifyType(node.Return, modifier)
+		return modifier(&cp)
+	case *Identifier:
+		cp := *node
+		return modifier(&cp)
+	case *IntegerLiteral:
+		cp := *node
+		return modifier(&cp)
+	case *StringLiteral:
+		cp := *node
+		return modifier(&cp)
+	case *Boolean:
+		cp := *node
+		return modifier(&cp)
+	case *Null:
+		cp := *node
+		return modifier(&cp)
+	case *WildcardPattern:
+		cp := *node
+		return modifier(&cp)
+	}
+
+	return modifier(node)
+}
+
+func modifyStatements(stmts []Statement, modifier ModifierFunc) []Statement {
+	if stmts == nil {
+		return nil
+	}
+	modified := make([]Statement, 0, len(stmts))
+	for _, s := range stmts {
+		if ms, ok := Modify(s, modifier).(Statement); ok {
+			modified = append(modified, ms)
+			continue
+		}
+		modified = append(modified, s)
+	}
+
+	return modified
+}
+
+func modifyExpressions(exps []Expression, modifier ModifierFunc) []Expression {
+	if exps == nil {
+		return nil
+	}
+	modified := make([]Expression, 0, len(exps))
+	for _, e := range exps {
+		modified = append(modified, modifyExpression(e, modifier))
+	}
+
+	return modified
+}
+
+func modifyExpression(exp Expression, modifier ModifierFunc) Expression {
+	if exp == nil {
+		return nil
+	}
+	if me, ok := Modify(exp, modifier).(Expression); ok {
+		return me
+	}
+
+	return exp
+}
+
+func modifyIdentifiers(idents []*Identifier, modifier ModifierFunc) []*Identifier {
+	if idents == nil {
+		return nil
+	}
+	modified := make([]*Identifier, 0, len(idents))
+	for _, i := range idents {
+		modified = append(modified, modifyIdentifier(i, modifier))
+	}
+
+	return modified
+}
+
+func modifyIdentifier(ident *Identifier, modifier ModifierFunc) *Identifier {
+	if ident == nil {
+		return nil
+	}
+	if mi, ok := Modify(ident, modifier).(*Identifier); ok {
+		return mi
+	}
+
+	return ident
+}
+
+func modifyBlock(block *BlockStatement, modifier ModifierFunc) *BlockStatement {
+	if block == nil {
+		return nil
+	}
+	if mb, ok := Modify(block, modifier).(*BlockStatement); ok {
+		return mb
+	}
+
+	return block
+}
+
+func modifyPattern(pat Pattern, modifier ModifierFunc) Pattern {
+	if pat == nil {
+		return nil
+	}
+	if mp, ok := Modify(pat, modifier).(Pattern); ok {
+		return mp
+	}
+
+	return pat
+}
+
+// modifyTypes keeps the nil entries of types, e.g. the unannotated parameters of a function
+func modifyTypes(types []TypeExpr, modifier ModifierFunc) []TypeExpr {
+	if types == nil {
+		return nil
+	}
+	modified := make([]TypeExpr, 0, len(types))
+	for _, t := range types {
+		modified = append(modified, modifyType(t, modifier))
+	}
+
+	return modified
+}
+
+func modifyType(t TypeExpr, modifier ModifierFunc) TypeExpr {
+	if t == nil {
+		return nil
+	}
+	if mt, ok := Modify(t, modifier).(TypeExpr); ok {
+		return mt
+	}
+
+	return t
+}
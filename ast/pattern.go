@@ -0,0 +1,168 @@
+package ast
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/geraldywy/monkey/token"
+)
+
+// Pattern is the left hand side of a match arm, describing the shape a value
+// must have for the arm to be selected, and the names bound on a successful match.
+type Pattern interface {
+	Node
+	patternNode()
+}
+
+type WildcardPattern struct {
+	Token *token.Token // the '_' token
+}
+
+func (wp *WildcardPattern) patternNode()         {}
+func (wp *WildcardPattern) TokenLiteral() string { return wp.Token.Literal }
+func (wp *WildcardPattern) String() string       { return "_" }
+
+type IdentifierPattern struct {
+	Token *token.Token // the token.IDENT token
+	Name  *Identifier
+}
+
+func (ip *IdentifierPattern) patternNode()         {}
+func (ip *IdentifierPattern) TokenLiteral() string { return ip.Token.Literal }
+func (ip *IdentifierPattern) String() string       { return ip.Name.String() }
+
+type LiteralPattern struct {
+	Token *token.Token // the first token of the literal
+	Value Expression   // IntegerLiteral, Boolean or a negated IntegerLiteral
+}
+
+func (lp *LiteralPattern) patternNode()         {}
+func (lp *LiteralPattern) TokenLiteral() string { return lp.Token.Literal }
+func (lp *LiteralPattern) String() string {
+	// a negated literal is written without the parentheses of a prefix expression
+	if pe, ok := lp.Value.(*PrefixExpression); ok {
+		return pe.Operator + pe.Right.String()
+	}
+	return lp.Value.String()
+}
+
+type ArrayPattern struct {
+	Token    *token.Token // the '[' token
+	Elements []Pattern
+	Rest     *Identifier // binds the remaining elements when non nil, e.g. ...tail
+}
+
+func (ap *ArrayPattern) patternNode()         {}
+func (ap *ArrayPattern) TokenLiteral() string { return ap.Token.Literal }
+func (ap *ArrayPattern) String() string {
+	var out bytes.Buffer
+	elems := []string{}
+	for _, e := range ap.Elements {
+		elems = append(elems, e.String())
+	}
+	if ap.Rest != nil {
+		elems = append(elems, "..."+ap.Rest.String())
+	}
+	out.WriteString("[")
+	out.WriteString(strings.Join(elems, ", "))
+	out.WriteString("]")
+	return out.String()
+}
+
+type HashPatternPair struct {
+	Key   *Identifier
+	Value Pattern
+	// Shorthand is set for a pair written as just the key, e.g. {name}. Value is then an
+	// IdentifierPattern binding the key itself.
+	Shorthand bool
+}
+
+type HashPattern struct {
+	Token *token.Token // the '{' token
+	Pairs []*HashPatternPair
+}
+
+func (hp *HashPattern) patternNode()         {}
+func (hp *HashPattern) TokenLiteral() string { return hp.Token.Literal }
+func (hp *HashPattern) String() string {
+	var out bytes.Buffer
+	pairs := []string{}
+	for _, p := range hp.Pairs {
+		if p.Shorthand {
+			pairs = append(pairs, p.Key.String())
+			continue
+		}
+		pairs = append(pairs, p.Key.String()+": "+p.Value.String())
+	}
+	out.WriteString("{")
+	out.WriteString(strings.Join(pairs, ", "))
+	out.WriteString("}")
+	return out.String()
+}
+
+// Bindings returns the identifiers bound by a pattern, in source order.
+func Bindings(p Pattern) []*Identifier {
+	switch p := p.(type) {
+	case *IdentifierPattern:
+		return []*Identifier{p.Name}
+	case *ArrayPattern:
+		idents := []*Identifier{}
+		for _, e := range p.Elements {
+			idents = append(idents, Bindings(e)...)
+		}
+		if p.Rest != nil && p.Rest.Value != "_" {
+			idents = append(idents, p.Rest)
+		}
+		return idents
+	case *HashPattern:
+		idents := []*Identifier{}
+		for _, pair := range p.Pairs {
+			idents = append(idents, Bindings(pair.Value)...)
+		}
+		return idents
+	}
+
+	return nil
+}
+
+type MatchArm struct {
+	Token   *token.Token // the first token of the pattern
+	Pattern Pattern
+	Guard   Expression // optional, the arm is only selected when this evaluates to true
+	Body    *BlockStatement
+}
+
+func (ma *MatchArm) TokenLiteral() string { return ma.Token.Literal }
+func (ma *MatchArm) String() string {
+	var out bytes.Buffer
+	out.WriteString(ma.Pattern.String())
+	if ma.Guard != nil {
+		out.WriteString(" if ")
+		out.WriteString(ma.Guard.String())
+	}
+	out.WriteString(" => ")
+	out.WriteString(ma.Body.String())
+	return out.String()
+}
+
+type MatchExpression struct {
+	Token   *token.Token // the 'match' token
+	Subject Expression
+	Arms    []*MatchArm
+}
+
+func (me *MatchExpression) expressionNode()      {}
+func (me *MatchExpression) TokenLiteral() string { return me.Token.Literal }
+func (me *MatchExpression) String() string {
+	var out bytes.Buffer
+	arms := []string{}
+	for _, a := range me.Arms {
+		arms = append(arms, a.String())
+	}
+	out.WriteString("match (")
+	out.WriteString(me.Subject.String())
+	out.WriteString(") { ")
+	out.WriteString(strings.Join(arms, ", "))
+	out.WriteString(" }")
+	return out.String()
+}
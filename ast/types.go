@@ -0,0 +1,48 @@
+package ast
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/geraldywy/monkey/token"
+)
+
+// TypeExpr is a type annotation, e.g. the int in let x: int = 1;. Annotations are only
+// read by the type checker, they have no effect at runtime.
+type TypeExpr interface {
+	Node
+	typeNode()
+}
+
+// NamedType is a type referred to by its name, e.g. int or null
+type NamedType struct {
+	Token *token.Token // the token.IDENT or token.NULL token
+	Name  string
+}
+
+func (nt *NamedType) typeNode()            {}
+func (nt *NamedType) TokenLiteral() string { return nt.Token.Literal }
+func (nt *NamedType) String() string       { return nt.Name }
+
+// FunctionType is the type of a function, e.g. fn(int, string) -> bool
+type FunctionType struct {
+	Token      *token.Token // the 'fn' token
+	Parameters []TypeExpr
+	Return     TypeExpr
+}
+
+func (ft *FunctionType) typeNode()            {}
+func (ft *FunctionType) TokenLiteral() string { return ft.Token.Literal }
+func (ft *FunctionType) String() string {
+	var out bytes.Buffer
+	params := []string{}
+	for _, p := range ft.Parameters {
+		params = append(params, p.String())
+	}
+	out.WriteString(ft.TokenLiteral())
+	out.WriteString("(")
+	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(") -> ")
+	out.WriteString(ft.Return.String())
+	return out.String()
+}
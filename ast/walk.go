@@ -0,0 +1,170 @@
+package ast
+
+// A Visitor's Visit method is invoked for each node encountered by Walk. If the
+// result visitor w is not nil, Walk visits each of the children of node with w,
+// followed by a call of w.Visit(nil).
+type Visitor interface {
+	Visit(node Node) (w Visitor)
+}
+
+// Walk traverses the tree rooted at node in depth first order, children are visited
+// in source order.
+func Walk(v Visitor, node Node) {
+	if v = v.Visit(node); v == nil {
+		return
+	}
+
+	switch n := node.(type) {
+	case *Program:
+		walkStatements(v, n.Statements)
+	case *LetStatement:
+		if n.Name != nil {
+			Walk(v, n.Name)
+		}
+		if n.Pattern != nil {
+			Walk(v, n.Pattern)
+		}
+		if n.Type != nil {
+			Walk(v, n.Type)
+		}
+		if n.Value != nil {
+			Walk(v, n.Value)
+		}
+	case *ReturnStatement:
+		if n.ReturnValue != nil {
+			Walk(v, n.ReturnValue)
+		}
+	case *ExpressionStatement:
+		if n.Expression != nil {
+			Walk(v, n.Expression)
+		}
+	case *BlockStatement:
+		walkStatements(v, n.Statements)
+	case *ThrowStatement:
+		Walk(v, n.Value)
+	case *DeferStatement:
+		Walk(v, n.Call)
+	case *ImportStatement:
+		Walk(v, n.Path)
+		Walk(v, n.Alias)
+	case *ExportStatement:
+		Walk(v, n.Statement)
+	case *PrefixExpression:
+		Walk(v, n.Right)
+	case *InfixExpression:
+		Walk(v, n.Left)
+		Walk(v, n.Right)
+	case *IfExpression:
+		Walk(v, n.Condition)
+		Walk(v, n.Consequence)
+		if n.Alternative != nil {
+			Walk(v, n.Alternative)
+		}
+	case *FunctionLiteral:
+		for i, p := range n.Parameters {
+			Walk(v, p)
+			if t := n.ParamType(i); t != nil {
+				Walk(v, t)
+			}
+			if def := n.Default(i); def != nil {
+				Walk(v, def)
+			}
+		}
+		if n.Rest != nil {
+			Walk(v, n.Rest)
+		}
+		if n.ReturnType != nil {
+			Walk(v, n.ReturnType)
+		}
+		Walk(v, n.Body)
+	case *MacroLiteral:
+		for _, p := range n.Parameters {
+			Walk(v, p)
+		}
+		Walk(v, n.Body)
+	case *CallExpression:
+		Walk(v, n.Function)
+		for _, a := range n.Arguments {
+			Walk(v, a)
+		}
+	case *SpreadExpression:
+		Walk(v, n.Value)
+	case *NamedArgument:
+		Walk(v, n.Name)
+		Walk(v, n.Value)
+	case *MemberExpression:
+		Walk(v, n.Object)
+		Walk(v, n.Property)
+	case *IndexExpression:
+		Walk(v, n.Left)
+		Walk(v, n.Index)
+	case *MatchExpression:
+		Walk(v, n.Subject)
+		for _, arm := range n.Arms {
+			Walk(v, arm)
+		}
+	case *MatchArm:
+		Walk(v, n.Pattern)
+		if n.Guard != nil {
+			Walk(v, n.Guard)
+		}
+		Walk(v, n.Body)
+	case *TryExpression:
+		Walk(v, n.Block)
+		if n.Catch != nil {
+			Walk(v, n.CatchParam)
+			Walk(v, n.Catch)
+		}
+		if n.Finally != nil {
+			Walk(v, n.Finally)
+		}
+	case *IdentifierPattern:
+		Walk(v, n.Name)
+	case *LiteralPattern:
+		Walk(v, n.Value)
+	case *ArrayPattern:
+		for _, e := range n.Elements {
+			Walk(v, e)
+		}
+		if n.Rest != nil {
+			Walk(v, n.Rest)
+		}
+	case *HashPattern:
+		for _, pair := range n.Pairs {
+			// the key of a shorthand pair is the identifier its value binds, visited once
+			if !pair.Shorthand {
+				Walk(v, pair.Key)
+			}
+			Walk(v, pair.Value)
+		}
+	case *FunctionType:
+		for _, p := range n.Parameters {
+			Walk(v, p)
+		}
+		Walk(v, n.Return)
+	}
+
+	v.Visit(nil)
+}
+
+func walkStatements(v Visitor, stmts []Statement) {
+	for _, s := range stmts {
+		Walk(v, s)
+	}
+}
+
+type inspector func(Node) bool
+
+func (f inspector) Visit(node Node) Visitor {
+	if f(node) {
+		return f
+	}
+	return nil
+}
+
+// Inspect traverses the tree rooted at node in depth first order, calling f for each
+// node. The children of a node are only visited if f returns true, and f is called
+// with nil once all of a node's children have been visited.
+func Inspect(node Node, f func(Node) bool) {
+	Walk(inspector(f), node)
+}
@@ -0,0 +1,237 @@
+package ast_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/geraldywy/monkey/ast"
+	"github.com/geraldywy/monkey/lexer"
+	"github.com/geraldywy/monkey/parser"
+)
+
+func parse(t *testing.T, input string) *ast.Program {
+	p := parser.New(lexer.New(input, "walk_test.go"))
+	program := p.ParseProgram()
+	if len(p.Errors) != 0 {
+		t.Fatalf("parser errors for %q: %v", input, p.Errors)
+	}
+	return program
+}
+
+// identifiers returns the names of all identifiers under node, in visiting order
+func identifiers(node ast.Node) []string {
+	names := []string{}
+	ast.Inspect(node, func(n ast.Node) bool {
+		if ident, ok := n.(*ast.Identifier); ok {
+			names = append(names, ident.Value)
+		}
+		return true
+	})
+	return names
+}
+
+func TestInspectVisitsEveryNode(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"let a = b + c;", []string{"a", "b", "c"}},
+		{"if (a) { b } else { c }", []string{"a", "b", "c"}},
+		{"fn(a, b = c, ...d) { e }", []string{"a", "b", "c", "d", "e"}},
+		{"(a) => b", []string{"a", "b"}},
+		{"macro(a) { b }", []string{"a", "b"}},
+		{"f(a, ...b, c: d)", []string{"f", "a", "b", "c", "d"}},
+		{"a?.b[c] ?? d", []string{"a", "b", "c", "d"}},
+		{"match (a) { [b, ...c] if d => e, {f: g} => h }", []string{"a", "b", "c", "d", "e", "f", "g", "h"}},
+		{"let {a, b: [c]} = d;", []string{"a", "b", "c", "d"}},
+		{"try { a } catch (b) { c } finally { d }", []string{"a", "b", "c", "d"}},
+		{"fn() { defer a(b); throw c; return d; }", []string{"a", "b", "c", "d"}},
+		{`import "x" as a; export let b = c;`, []string{"a", "b", "c"}},
+	}
+	for _, tt := range tests {
+		got := identifiers(parse(t, tt.input))
+		if fmt.Sprint(got) != fmt.Sprint(tt.expected) {
+			t.Errorf("identifiers of %q wrong. want=%v, got=%v", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestInspectVisitsShorthandKeyOnce(t *testing.T) {
+	program := parse(t, "let {a, b: d} = c;")
+	visits := make(map[*ast.Identifier]int)
+	ast.Inspect(program, func(n ast.Node) bool {
+		if ident, ok := n.(*ast.Identifier); ok {
+			visits[ident]++
+		}
+		return true
+	})
+	pairs := program.Statements[0].(*ast.LetStatement).Pattern.(*ast.HashPattern).Pairs
+	tests := []struct {
+		ident    *ast.Identifier
+		expected int
+	}{
+		{pairs[0].Key, 1},
+		{pairs[1].Key, 1},
+		{pairs[1].Value.(*ast.IdentifierPattern).Name, 1},
+	}
+	for i, tt := range tests {
+		if visits[tt.ident] != tt.expected {
+			t.Errorf("tests[%d] - %s visited wrong number of times. want=%d, got=%d", i, tt.ident, tt.expected, visits[tt.ident])
+		}
+	}
+	if len(visits) != 4 {
+		t.Errorf("wrong number of identifiers visited. want=4, got=%d", len(visits))
+	}
+}
+
+func TestInspectShorthandIsStructural(t *testing.T) {
+	// a rebuilt pair marked shorthand has its own key, which is still not visited
+	pattern := &ast.HashPattern{Pairs: []*ast.HashPatternPair{{
+		Key:       &ast.Identifier{Value: "a"},
+		Value:     &ast.IdentifierPattern{Name: &ast.Identifier{Value: "a"}},
+		Shorthand: true,
+	}}}
+	visits := 0
+	ast.Inspect(pattern, func(n ast.Node) bool {
+		if _, ok := n.(*ast.Identifier); ok {
+			visits++
+		}
+		return true
+	})
+	if visits != 1 {
+		t.Errorf("shorthand pair visited wrong number of identifiers. want=1, got=%d", visits)
+	}
+	if pattern.String() != "{a}" {
+		t.Errorf("pattern.String() wrong. want=%q, got=%q", "{a}", pattern.String())
+	}
+}
+
+func TestInspectPruning(t *testing.T) {
+	program := parse(t, "let f = fn(a) { b }; c;")
+	names := []string{}
+	ast.Inspect(program, func(n ast.Node) bool {
+		if _, ok := n.(*ast.FunctionLiteral); ok {
+			return false
+		}
+		if ident, ok := n.(*ast.Identifier); ok {
+			names = append(names, ident.Value)
+		}
+		return true
+	})
+	if fmt.Sprint(names) != "[f c]" {
+		t.Errorf("function literal was not pruned. got=%v", names)
+	}
+}
+
+type depthVisitor struct {
+	depth int
+	out   *[]string
+}
+
+func (v depthVisitor) Visit(node ast.Node) ast.Visitor {
+	if node == nil {
+		*v.out = append(*v.out, strings.Repeat(" ", v.depth-1)+"end")
+		return nil
+	}
+	*v.out = append(*v.out, strings.Repeat(" ", v.depth)+fmt.Sprintf("%T", node))
+	return depthVisitor{depth: v.depth + 1, out: v.out}
+}
+
+func TestWalk(t *testing.T) {
+	out := []string{}
+	ast.Walk(depthVisitor{out: &out}, parse(t, "-a;"))
+	expected := []string{
+		"*ast.Program",
+		" *ast.ExpressionStatement",
+		"  *ast.PrefixExpression",
+		"   *ast.Identifier",
+		"   end",
+		"  end",
+		" end",
+		"end",
+	}
+	if strings.Join(out, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("walk order wrong. want=\n%s\ngot=\n%s", strings.Join(expected, "\n"), strings.Join(out, "\n"))
+	}
+}
+
+func TestModify(t *testing.T) {
+	rename := func(node ast.Node) ast.Node {
+		ident, ok := node.(*ast.Identifier)
+		if !ok {
+			return node
+		}
+		ident.Value = strings.ToUpper(ident.Value)
+		return ident
+	}
+	one := func(node ast.Node) ast.Node {
+		integer, ok := node.(*ast.IntegerLiteral)
+		if !ok || integer.Value != 1 {
+			return node
+		}
+		return parse(t, "2").Statements[0].(*ast.ExpressionStatement).Expression
+	}
+	retype := func(node ast.Node) ast.Node {
+		if nt, ok := node.(*ast.NamedType); ok && nt.Name == "int" {
+			nt.Name = "string"
+		}
+		return node
+	}
+	tests := []struct {
+		input    string
+		modifier ast.ModifierFunc
+		expected string
+	}{
+		{"1 + 1", one, "(2 + 2)"},
+		{"if (1) { 1 } else { 1 }", one, "if (2) { 2 } else { 2 }"},
+		{"fn(a, b = 1, ...c) { a }", rename, "fn(A, B = 1, ...C) { A }"},
+		{"match (1) { [a] if 1 => 1 }", one, "match (2) { [a] if 2 => { 2 } }"},
+		{"match (x) { {a, b: -1} => c }", rename, "match (X) { {A, B: -1} => { C } }"},
+		{"let [a, ...b] = c;", rename, "let [A, ...B] = C;"},
+		{"f(a: 1)?.b[1]", one, "((f(a: 2)?.b)[2])"},
+		{"try { 1 } catch (e) { 1 } finally { 1 }", one, "try { 2 } catch (e) { 2 } finally { 2 }"},
+		{"fn() { defer f(1); throw 1; return 1; }", one, "fn() { defer f(2);throw 2;return 2; }"},
+		{"let x: int = 1;", retype, "let x: string = 1;"},
+		{"fn(a: int, b) -> fn(int, bool) -> int { a }", retype, "fn(a: string, b) -> fn(string, bool) -> string { a }"},
+	}
+	for _, tt := range tests {
+		program := parse(t, tt.input)
+		original := program.String()
+		modified := ast.Modify(program, tt.modifier)
+		if modified.String() != tt.expected {
+			t.Errorf("modified %q wrong. want=%q, got=%q", tt.input, tt.expected, modified.String())
+		}
+		if program.String() != original {
+			t.Errorf("original tree of %q was changed. got=%q", tt.input, program.String())
+		}
+	}
+}
+
+func TestModifyLeavesOriginalUntouched(t *testing.T) {
+	program := parse(t, "let a = fn(x) { x + 1 };")
+	original := program.String()
+	modified := ast.Modify(program, func(node ast.Node) ast.Node {
+		if infix, ok := node.(*ast.InfixExpression); ok {
+			infix.Operator = "-"
+		}
+		return node
+	})
+	if program.String() != original {
+		t.Errorf("original tree was changed. want=%q, got=%q", original, program.String())
+	}
+	if modified.String() != "let a = fn(x) { (x - 1) };" {
+		t.Errorf("modified tree wrong. got=%q", modified.String())
+	}
+}
+
+func TestModifyCopiesTypes(t *testing.T) {
+	program := parse(t, "fn(a: int, b) -> int { a };")
+	original := program.String()
+	modified := ast.Modify(program, func(node ast.Node) ast.Node { return node }).(*ast.Program)
+	fn := modified.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.FunctionLiteral)
+	fn.ParamTypes[0] = nil
+	if program.String() != original {
+		t.Errorf("original parameter types were shared. want=%q, got=%q", original, program.String())
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/geraldywy/monkey/module"
+	"github.com/geraldywy/monkey/types"
+)
+
+func checkCmd(args []string) int {
+	fs := flag.NewFlagSet("check", flag.ExitOnError)
+	infer := fs.Bool("infer", false, "infer the types of unannotated code, printing the top level bindings")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: monkey check [-infer] <file>...")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	code := 0
+	loader := module.NewLoader()
+	for _, path := range fs.Args() {
+		program, ok := parseFile(path)
+		if !ok {
+			code = 1
+			continue
+		}
+		var errs []error
+		if *infer {
+			var inf *types.Inference
+			inf, errs = types.Infer(program, path)
+			for _, b := range inf.Bindings {
+				fmt.Println(b)
+			}
+		} else {
+			_, errs = types.Check(program, path)
+		}
+		for _, err := range errs {
+			fmt.Fprintln(os.Stderr, err)
+			code = 1
+		}
+		// imports are loaded to check that the names used through them are exported
+		if _, err := loader.Load(path); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			code = 1
+		}
+	}
+
+	return code
+}
@@ -0,0 +1,50 @@
+package diff
+
+import "testing"
+
+func TestUnified(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want string
+	}{
+		{"equal", "a\nb\n", "a\nb\n", ""},
+		{
+			"change",
+			"a\nb\nc\n",
+			"a\nB\nc\n",
+			"--- old\n+++ new\n@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n",
+		},
+		{
+			"insert into empty",
+			"",
+			"a\n",
+			"--- old\n+++ new\n@@ -0,0 +1,1 @@\n+a\n",
+		},
+		{
+			"delete all",
+			"a\nb\n",
+			"",
+			"--- old\n+++ new\n@@ -1,2 +0,0 @@\n-a\n-b\n",
+		},
+		{
+			"separate hunks",
+			"1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n",
+			"x\n2\n3\n4\n5\n6\n7\n8\n9\n10\ny\n",
+			"--- old\n+++ new\n" +
+				"@@ -1,4 +1,4 @@\n-1\n+x\n 2\n 3\n 4\n" +
+				"@@ -8,4 +8,4 @@\n 8\n 9\n 10\n-11\n+y\n",
+		},
+		{
+			"merged hunks",
+			"1\n2\n3\n4\n5\n",
+			"x\n2\n3\n4\ny\n",
+			"--- old\n+++ new\n@@ -1,5 +1,5 @@\n-1\n+x\n 2\n 3\n 4\n-5\n+y\n",
+		},
+	}
+	for _, tt := range tests {
+		if got := Unified("old", "new", tt.a, tt.b); got != tt.want {
+			t.Errorf("%s - diff wrong.\nwant:\n%s\ngot:\n%s", tt.name, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/geraldywy/monkey/diff"
+	"github.com/geraldywy/monkey/format"
+)
+
+func fmtCmd(args []string) int {
+	fs := flag.NewFlagSet("fmt", flag.ExitOnError)
+	write := fs.Bool("w", false, "write the result to the file instead of stdout")
+	showDiff := fs.Bool("d", false, "print a diff of the changes instead of the result")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: monkey fmt [-w] [-d] <file>...")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	code := 0
+	for _, path := range fs.Args() {
+		if !fmtFile(path, *write, *showDiff) {
+			code = 1
+		}
+	}
+
+	return code
+}
+
+func fmtFile(path string, write, showDiff bool) bool {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return false
+	}
+	out, err := format.Source(src, path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return false
+	}
+
+	if showDiff {
+		fmt.Print(diff.Unified(path+".orig", path, string(src), string(out)))
+	}
+	if write {
+		if string(out) == string(src) {
+			return true
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return false
+		}
+		if err := os.WriteFile(path, out, info.Mode().Perm()); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return false
+		}
+		return true
+	}
+	if !showDiff {
+		os.Stdout.Write(out)
+	}
+
+	return true
+}
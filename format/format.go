@@ -0,0 +1,241 @@
+// Package format prints Monkey programs in their canonical form: one statement per line,
+// blocks indented with tabs, and only the parentheses the grammar needs.
+package format
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/geraldywy/monkey/ast"
+	"github.com/geraldywy/monkey/lexer"
+	"github.com/geraldywy/monkey/parser"
+	"github.com/geraldywy/monkey/token"
+)
+
+// ParseErrors holds the errors reported while parsing the source being formatted
+type ParseErrors []error
+
+func (pe ParseErrors) Error() string {
+	msgs := make([]string, 0, len(pe))
+	for _, err := range pe {
+		msgs = append(msgs, err.Error())
+	}
+
+	return strings.Join(msgs, "\n")
+}
+
+// Source formats the program in src, keeping its comments. Comments are kept on the line
+// they trail, or placed on their own line before the statement that follows them.
+// fileName is only used in error messages.
+func Source(src []byte, fileName string) ([]byte, error) {
+	p := parser.New(lexer.New(string(src), fileName))
+	program := p.ParseProgram()
+	if len(p.Errors) != 0 {
+		return nil, ParseErrors(p.Errors)
+	}
+
+	pr := &printer{}
+	if err := pr.scan(string(src), fileName); err != nil {
+		return nil, err
+	}
+	pr.statements(program.Statements)
+	pr.flushComments(nil)
+	if pr.err != nil {
+		return nil, pr.err
+	}
+
+	return pr.bytes(), nil
+}
+
+// Node writes the canonical form of node to w. node is a program, statement, expression
+// or pattern, and is printed without comments.
+func Node(w io.Writer, node ast.Node) error {
+	pr := &printer{}
+	switch node := node.(type) {
+	case *ast.Program:
+		pr.statements(node.Statements)
+	case ast.Statement:
+		pr.statement(node, nil)
+	case ast.Expression:
+		pr.expr(node, parser.LOWEST)
+	case ast.Pattern:
+		pr.pattern(node)
+	default:
+		return errors.New(fmt.Sprintf("cannot format node %T", node))
+	}
+	if pr.err != nil {
+		return pr.err
+	}
+	out := pr.bytes()
+	// a lone expression or pattern does not end a line
+	if _, ok := node.(*ast.Program); !ok {
+		out = bytes.TrimSuffix(out, []byte("\n"))
+	}
+	_, err := w.Write(out)
+
+	return err
+}
+
+type pos struct {
+	line, col int
+}
+
+func posOf(tkn *token.Token) pos {
+	return pos{line: tkn.Line, col: tkn.Column}
+}
+
+func (p pos) before(other pos) bool {
+	return p.line < other.line || (p.line == other.line && p.col < other.col)
+}
+
+type printer struct {
+	lines  []string
+	cur    strings.Builder // the line being written, not yet in lines
+	indent int
+	fresh  bool // nothing has been written since the current block was opened
+	err    error
+
+	// only set when formatting source, used to place comments and preserve blank lines
+	tokens   []*token.Token // every token in the source, comments included
+	index    map[pos]int    // index into tokens of the token at a position
+	closing  map[pos]*token.Token
+	comments []*token.Token // comments not yet printed, in source order
+}
+
+// scan lexes src a second time, recording its comments and where each '{' is closed
+func (p *printer) scan(src, fileName string) error {
+	l := lexer.New(src, fileName)
+	l.ScanComments = true
+	p.index = make(map[pos]int)
+	p.closing = make(map[pos]*token.Token)
+	var open []*token.Token
+	for {
+		tkn, err := l.NextToken()
+		if err != nil {
+			return err
+		}
+		if tkn.Type == token.EOF {
+			return nil
+		}
+		p.index[posOf(tkn)] = len(p.tokens)
+		p.tokens = append(p.tokens, tkn)
+		switch tkn.Type {
+		case token.COMMENT:
+			p.comments = append(p.comments, tkn)
+		case token.LBRACE:
+			open = append(open, tkn)
+		case token.RBRACE:
+			if len(open) != 0 {
+				p.closing[posOf(open[len(open)-1])] = tkn
+				open = open[:len(open)-1]
+			}
+		}
+	}
+}
+
+func (p *printer) bytes() []byte {
+	if p.cur.Len() != 0 {
+		p.newline()
+	}
+	if len(p.lines) == 0 {
+		return nil
+	}
+
+	return []byte(strings.Join(p.lines, "\n") + "\n")
+}
+
+func (p *printer) write(s string) {
+	if p.cur.Len() == 0 {
+		p.cur.WriteString(strings.Repeat("\t", p.indent))
+	}
+	p.cur.WriteString(s)
+	p.fresh = false
+}
+
+func (p *printer) newline() {
+	p.lines = append(p.lines, p.cur.String())
+	p.cur.Reset()
+}
+
+// separate keeps a single blank line before tkn if the source had at least one, except at
+// the start of the file or of a block
+func (p *printer) separate(tkn *token.Token) {
+	if tkn == nil || len(p.lines) == 0 || p.fresh || p.lines[len(p.lines)-1] == "" {
+		return
+	}
+	i, ok := p.index[posOf(tkn)]
+	if !ok || i == 0 || tkn.Line-p.tokens[i-1].Line < 2 {
+		return
+	}
+	p.lines = append(p.lines, "")
+}
+
+// flushComments prints the comments before tkn, or all remaining ones when tkn is nil.
+// It is only called between lines.
+func (p *printer) flushComments(tkn *token.Token) {
+	for len(p.comments) != 0 {
+		c := p.comments[0]
+		if tkn != nil && !posOf(c).before(posOf(tkn)) {
+			return
+		}
+		p.comments = p.comments[1:]
+
+		i := p.index[posOf(c)]
+		trailing := i > 0 && p.tokens[i-1].Line == c.Line
+		if trailing && len(p.lines) != 0 && p.lines[len(p.lines)-1] != "" {
+			p.lines[len(p.lines)-1] += " " + c.Literal
+			continue
+		}
+		p.separate(c)
+		p.write(c.Literal)
+		p.newline()
+	}
+}
+
+// leading prints what comes before a line starting at tkn
+func (p *printer) leading(tkn *token.Token) {
+	if tkn == nil {
+		return
+	}
+	p.flushComments(tkn)
+	p.separate(tkn)
+}
+
+// closeOf returns the '}' closing the '{' at tkn, or nil if it is unknown
+func (p *printer) closeOf(tkn *token.Token) *token.Token {
+	if tkn == nil {
+		return nil
+	}
+
+	return p.closing[posOf(tkn)]
+}
+
+// matchBody returns the '{' opening the arms of a match expression
+func (p *printer) matchBody(me *ast.MatchExpression) *token.Token {
+	if me.Token == nil {
+		return nil
+	}
+	i, ok := p.index[posOf(me.Token)]
+	if !ok {
+		return nil
+	}
+	// skip the parenthesised subject
+	depth := 0
+	for i++; i < len(p.tokens); i++ {
+		switch p.tokens[i].Type {
+		case token.LPAREN:
+			depth++
+		case token.RPAREN:
+			depth--
+		case token.LBRACE:
+			if depth == 0 {
+				return p.tokens[i]
+			}
+		}
+	}
+
+	return nil
+}
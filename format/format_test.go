@@ -0,0 +1,166 @@
+package format_test
+
+import (
+	"bytes"
+	goast "go/ast"
+	goparser "go/parser"
+	gotoken "go/token"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/geraldywy/monkey/ast"
+	"github.com/geraldywy/monkey/format"
+	"github.com/geraldywy/monkey/lexer"
+	"github.com/geraldywy/monkey/parser"
+)
+
+func TestSource(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x=1+2*3;", "let x = 1 + 2 * 3;\n"},
+		{"(1 + 2) * 3;", "(1 + 2) * 3;\n"},
+		{"1 - (2 - 3); (1 - 2) - 3;", "1 - (2 - 3);\n1 - 2 - 3;\n"},
+		{"(2 ** 3) ** 2; 2 ** (3 ** 2);", "(2 ** 3) ** 2;\n2 ** 3 ** 2;\n"},
+		{"-(-x); !(-x); (-x).y; -(x.y);", "- -x;\n!-x;\n(-x).y;\n-x.y;\n"},
+		{"f((x) => x, y => y * 2)", "f((x) => x, (y) => y * 2);\n"},
+		{"((x) => x)(1); (x => x) + 1", "((x) => x)(1);\n((x) => x) + 1;\n"},
+		{`"a\"b\\c\n"`, "\"a\\\"b\\\\c\\n\";\n"},
+		{"if (x) { 1 } else { 2 }", "if (x) {\n\t1;\n} else {\n\t2;\n}\n"},
+		{"if (x) { 1 }; (y) => y", "if (x) {\n\t1;\n};\n(y) => y;\n"},
+		{"if (x) { 1 }; -y", "if (x) {\n\t1;\n};\n-y;\n"},
+		{"if (x) { 1 } y", "if (x) {\n\t1;\n}\ny;\n"},
+		{"fn() {}", "fn() {};\n"},
+		{"match (x) { 1 => a, _ => { b } }", "match (x) {\n\t1 => a,\n\t_ => {\n\t\tb;\n\t},\n}\n"},
+		{"match (x) { n if n > 0 => n }", "match (x) {\n\tn if n > 0 => n,\n}\n"},
+		{"let [a, [b], ...c] = xs; let {k: [d, _]} = h;", "let [a, [b], ...c] = xs;\nlet {k: [d, _]} = h;\n"},
+		{"a?.b?[c] ?? d", "a?.b?[c] ?? d;\n"},
+		{"let f:fn(int)->int=fn(a:int,b:string=\"\")->int{a};", "let f: fn(int) -> int = fn(a: int, b: string = \"\") -> int {\n\ta;\n};\n"},
+		{"(x:int)->int=>x", "(x: int) -> int => x;\n"},
+		{"let a = 1;\n\n\n\nlet b = 2;", "let a = 1;\n\nlet b = 2;\n"},
+		{"fn() {\n\n  x\n\n}", "fn() {\n\tx;\n};\n"},
+		{"let a = 1; // one\n// two\nlet b = 2;", "let a = 1; // one\n// two\nlet b = 2;\n"},
+		{"fn() {\n  x // last   \n  // end\n}", "fn() {\n\tx; // last\n\t// end\n};\n"},
+		{"fn() { // only\n}", "fn() { // only\n};\n"},
+		{"match (x) {\n  // first\n  1 => a,\n  // end\n}", "match (x) {\n\t// first\n\t1 => a,\n\t// end\n}\n"},
+		{"// just a comment", "// just a comment\n"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		out, err := format.Source([]byte(tt.input), "format_test.go")
+		if err != nil {
+			t.Errorf("unexpected error formatting %q: %s", tt.input, err)
+			continue
+		}
+		if string(out) != tt.expected {
+			t.Errorf("formatting %q - expected=%q, got=%q", tt.input, tt.expected, string(out))
+		}
+	}
+}
+
+func TestSourceErrors(t *testing.T) {
+	_, err := format.Source([]byte("let = 5;"), "format_test.go")
+	if _, ok := err.(format.ParseErrors); !ok {
+		t.Fatalf("expected format.ParseErrors, got=%T (%v)", err, err)
+	}
+}
+
+func TestGolden(t *testing.T) {
+	inputs, err := filepath.Glob(filepath.Join("testdata", "*.input"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, input := range inputs {
+		src, err := os.ReadFile(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, err := os.ReadFile(strings.TrimSuffix(input, ".input") + ".golden")
+		if err != nil {
+			t.Fatal(err)
+		}
+		out, err := format.Source(src, input)
+		if err != nil {
+			t.Fatalf("%s - unexpected error: %s", input, err)
+		}
+		if !bytes.Equal(out, want) {
+			t.Errorf("%s - output differs from golden file.\nwant:\n%s\ngot:\n%s", input, want, out)
+		}
+	}
+}
+
+// TestIdempotent formats every string literal in the parser tests that parses, checking
+// the result parses to the same program and formats to itself.
+func TestIdempotent(t *testing.T) {
+	inputs := parserTestInputs(t)
+	if len(inputs) == 0 {
+		t.Fatal("no inputs found in the parser tests")
+	}
+	for _, input := range inputs {
+		p := parser.New(lexer.New(input, "format_test.go"))
+		program := p.ParseProgram()
+		if len(p.Errors) != 0 {
+			continue
+		}
+
+		out, err := format.Source([]byte(input), "format_test.go")
+		if err != nil {
+			t.Errorf("unexpected error formatting %q: %s", input, err)
+			continue
+		}
+		p = parser.New(lexer.New(string(out), "format_test.go"))
+		formatted := p.ParseProgram()
+		if len(p.Errors) != 0 {
+			t.Errorf("formatting %q gave %q, which does not parse: %v", input, out, p.Errors)
+			continue
+		}
+		if !ast.Equal(program, formatted) {
+			t.Errorf("formatting %q changed the program. want=%q, got=%q", input, program.String(), formatted.String())
+		}
+		again, err := format.Source(out, "format_test.go")
+		if err != nil || !bytes.Equal(again, out) {
+			t.Errorf("formatting %q is not idempotent. first=%q, second=%q", input, out, again)
+		}
+	}
+}
+
+func parserTestInputs(t *testing.T) []string {
+	t.Helper()
+	fset := gotoken.NewFileSet()
+	f, err := goparser.ParseFile(fset, filepath.Join("..", "parser", "parser_test.go"), nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var inputs []string
+	goast.Inspect(f, func(n goast.Node) bool {
+		if lit, ok := n.(*goast.BasicLit); ok && lit.Kind == gotoken.STRING {
+			if s, err := strconv.Unquote(lit.Value); err == nil {
+				inputs = append(inputs, s)
+			}
+		}
+		return true
+	})
+
+	return inputs
+}
+
+func TestNode(t *testing.T) {
+	p := parser.New(lexer.New("let f = fn(a) { a * (a + 1) };", "format_test.go"))
+	program := p.ParseProgram()
+	if len(p.Errors) != 0 {
+		t.Fatal(p.Errors)
+	}
+
+	var buf bytes.Buffer
+	if err := format.Node(&buf, program.Statements[0]); err != nil {
+		t.Fatal(err)
+	}
+	if want := "let f = fn(a) {\n\ta * (a + 1);\n};"; buf.String() != want {
+		t.Errorf("expected=%q, got=%q", want, buf.String())
+	}
+}
@@ -0,0 +1,415 @@
+package format
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/geraldywy/monkey/ast"
+	"github.com/geraldywy/monkey/parser"
+	"github.com/geraldywy/monkey/token"
+)
+
+// atom is the precedence of expressions that never need parentheses
+const atom = parser.INDEX + 1
+
+func (p *printer) statements(stmts []ast.Statement) {
+	for i, s := range stmts {
+		var next ast.Statement
+		if i+1 < len(stmts) {
+			next = stmts[i+1]
+		}
+		p.leading(ast.TokenOf(s))
+		p.statement(s, next)
+		p.newline()
+	}
+}
+
+// statement prints s on the current line, next is the statement following it in the same
+// block, if any
+func (p *printer) statement(s ast.Statement, next ast.Statement) {
+	switch s := s.(type) {
+	case *ast.LetStatement:
+		p.let(s)
+	case *ast.ReturnStatement:
+		p.write("return")
+		if s.ReturnValue != nil {
+			p.write(" ")
+			p.expr(s.ReturnValue, parser.LOWEST)
+		}
+		p.write(";")
+	case *ast.ThrowStatement:
+		p.write("throw ")
+		p.expr(s.Value, parser.LOWEST)
+		p.write(";")
+	case *ast.DeferStatement:
+		p.write("defer ")
+		p.expr(s.Call, parser.LOWEST)
+		p.write(";")
+	case *ast.ImportStatement:
+		p.write("import ")
+		p.expr(s.Path, parser.LOWEST)
+		p.write(" as ")
+		p.expr(s.Alias, parser.LOWEST)
+		p.write(";")
+	case *ast.ExportStatement:
+		p.write("export ")
+		p.let(s.Statement)
+	case *ast.ExpressionStatement:
+		p.expr(s.Expression, parser.LOWEST)
+		if needsSemicolon(s, next) {
+			p.write(";")
+		}
+	case *ast.BlockStatement:
+		p.block(s)
+	default:
+		p.unsupported(s)
+	}
+}
+
+func (p *printer) let(ls *ast.LetStatement) {
+	p.write("let ")
+	if ls.Pattern != nil {
+		p.pattern(ls.Pattern)
+	} else {
+		p.expr(ls.Name, parser.LOWEST)
+	}
+	if ls.Type != nil {
+		p.write(": " + ls.Type.String())
+	}
+	p.write(" = ")
+	if ls.Value != nil {
+		p.expr(ls.Value, parser.LOWEST)
+	}
+	p.write(";")
+}
+
+// needsSemicolon reports whether s is terminated with a ';'. Statements ending in a block,
+// e.g. an if expression, only need one when the next statement would otherwise continue
+// them, e.g. a grouped expression read as a call.
+func needsSemicolon(s *ast.ExpressionStatement, next ast.Statement) bool {
+	switch s.Expression.(type) {
+	case *ast.IfExpression, *ast.MatchExpression, *ast.TryExpression:
+	default:
+		return true
+	}
+	if next == nil {
+		return false
+	}
+	pr := &printer{}
+	pr.statement(next, nil)
+	if pr.cur.Len() == 0 {
+		return true
+	}
+	first := pr.cur.String()[0]
+
+	return !(isWordChar(first) || first == '"')
+}
+
+func isWordChar(ch byte) bool {
+	return (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z') || (ch >= '0' && ch <= '9') || ch == '_'
+}
+
+func (p *printer) block(b *ast.BlockStatement) {
+	p.write("{")
+	closeTkn := p.closeOf(b.Token)
+	if len(b.Statements) == 0 && !p.hasCommentsBefore(closeTkn) {
+		p.write("}")
+		return
+	}
+	p.newline()
+	p.indent++
+	p.fresh = true
+	p.statements(b.Statements)
+	if closeTkn != nil {
+		p.flushComments(closeTkn)
+	}
+	p.indent--
+	p.write("}")
+}
+
+func (p *printer) hasCommentsBefore(tkn *token.Token) bool {
+	return tkn != nil && len(p.comments) != 0 && posOf(p.comments[0]).before(posOf(tkn))
+}
+
+// body prints the body of an arrow function or match arm, keeping a concise body concise
+func (p *printer) body(b *ast.BlockStatement) {
+	if exp, ok := conciseBody(b); ok {
+		p.expr(exp, parser.LOWEST)
+		return
+	}
+	p.block(b)
+}
+
+func conciseBody(b *ast.BlockStatement) (ast.Expression, bool) {
+	if b.Token == nil || b.Token.Type == token.LBRACE || len(b.Statements) != 1 {
+		return nil, false
+	}
+	es, ok := b.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		return nil, false
+	}
+
+	return es.Expression, true
+}
+
+// expr prints e, wrapped in parentheses if it binds looser than need
+func (p *printer) expr(e ast.Expression, need int) {
+	if precedence(e) < need {
+		p.write("(")
+		defer p.write(")")
+	}
+
+	switch e := e.(type) {
+	case *ast.Identifier:
+		p.write(e.Value)
+	case *ast.IntegerLiteral:
+		if e.Big != nil {
+			p.write(e.Big.String())
+		} else {
+			p.write(strconv.FormatInt(e.Value, 10))
+		}
+	case *ast.StringLiteral:
+		p.write(e.String())
+	case *ast.Boolean:
+		p.write(strconv.FormatBool(e.Value))
+	case *ast.Null:
+		p.write("null")
+	case *ast.PrefixExpression:
+		p.write(e.Operator)
+		// keep e.g. - -x from being read as --x
+		if inner, ok := e.Right.(*ast.PrefixExpression); ok {
+			if _, isToken := token.DoubleToken[e.Operator[len(e.Operator)-1:]+inner.Operator[:1]]; isToken {
+				p.write(" ")
+			}
+		}
+		p.expr(e.Right, parser.PREFIX)
+	case *ast.InfixExpression:
+		pred, assoc, _ := parser.InfixPrecedence(token.TokenType(e.Operator))
+		left, right := pred, pred+1
+		if assoc == parser.RIGHT {
+			left, right = pred+1, pred
+		}
+		p.expr(e.Left, left)
+		p.write(" " + e.Operator + " ")
+		// a prefix operator is always read as such, wherever its operand ends
+		if _, ok := e.Right.(*ast.PrefixExpression); ok {
+			right = parser.LOWEST
+		}
+		p.expr(e.Right, right)
+	case *ast.IfExpression:
+		p.write("if (")
+		p.expr(e.Condition, parser.LOWEST)
+		p.write(") ")
+		p.block(e.Consequence)
+		if e.Alternative != nil {
+			p.write(" else ")
+			p.block(e.Alternative)
+		}
+	case *ast.FunctionLiteral:
+		if e.Arrow {
+			p.params(e.Parameters, e.ParamTypes, e.Defaults, e.Rest)
+			p.returnType(e.ReturnType)
+			p.write(" => ")
+			p.body(e.Body)
+			return
+		}
+		p.write("fn")
+		p.params(e.Parameters, e.ParamTypes, e.Defaults, e.Rest)
+		p.returnType(e.ReturnType)
+		p.write(" ")
+		p.block(e.Body)
+	case *ast.MacroLiteral:
+		p.write("macro")
+		p.params(e.Parameters, nil, nil, nil)
+		p.write(" ")
+		p.block(e.Body)
+	case *ast.CallExpression:
+		p.expr(e.Function, parser.CALL)
+		p.write("(")
+		for i, arg := range e.Arguments {
+			if i > 0 {
+				p.write(", ")
+			}
+			p.expr(arg, parser.LOWEST)
+		}
+		p.write(")")
+	case *ast.SpreadExpression:
+		p.write("...")
+		p.expr(e.Value, parser.LOWEST)
+	case *ast.NamedArgument:
+		p.write(e.Name.Value + ": ")
+		p.expr(e.Value, parser.LOWEST)
+	case *ast.MemberExpression:
+		p.expr(e.Object, parser.CALL)
+		if e.Optional {
+			p.write("?.")
+		} else {
+			p.write(".")
+		}
+		p.write(e.Property.Value)
+	case *ast.IndexExpression:
+		p.expr(e.Left, parser.CALL)
+		if e.Optional {
+			p.write("?[")
+		} else {
+			p.write("[")
+		}
+		p.expr(e.Index, parser.LOWEST)
+		p.write("]")
+	case *ast.MatchExpression:
+		p.match(e)
+	case *ast.TryExpression:
+		p.write("try ")
+		p.block(e.Block)
+		if e.Catch != nil {
+			p.write(" catch (" + e.CatchParam.Value + ") ")
+			p.block(e.Catch)
+		}
+		if e.Finally != nil {
+			p.write(" finally ")
+			p.block(e.Finally)
+		}
+	default:
+		p.unsupported(e)
+	}
+}
+
+// precedence returns how tightly e binds when printed without parentheses
+func precedence(e ast.Expression) int {
+	switch e := e.(type) {
+	case *ast.PrefixExpression:
+		return parser.PREFIX
+	case *ast.InfixExpression:
+		pred, _, ok := parser.InfixPrecedence(token.TokenType(e.Operator))
+		if !ok {
+			return parser.LOWEST
+		}
+		return pred
+	case *ast.CallExpression:
+		return parser.CALL
+	case *ast.MemberExpression, *ast.IndexExpression:
+		return parser.INDEX
+	case *ast.FunctionLiteral:
+		// an arrow function's body extends as far right as it can
+		if e.Arrow {
+			return parser.LOWEST
+		}
+	}
+
+	return atom
+}
+
+func (p *printer) params(params []*ast.Identifier, types []ast.TypeExpr, defaults []ast.Expression, rest *ast.Identifier) {
+	p.write("(")
+	for i, param := range params {
+		if i > 0 {
+			p.write(", ")
+		}
+		p.write(param.Value)
+		if i < len(types) && types[i] != nil {
+			p.write(": " + types[i].String())
+		}
+		if i < len(defaults) && defaults[i] != nil {
+			p.write(" = ")
+			p.expr(defaults[i], parser.LOWEST)
+		}
+	}
+	if rest != nil {
+		if len(params) > 0 {
+			p.write(", ")
+		}
+		p.write("..." + rest.Value)
+	}
+	p.write(")")
+}
+
+func (p *printer) returnType(t ast.TypeExpr) {
+	if t != nil {
+		p.write(" -> " + t.String())
+	}
+}
+
+func (p *printer) match(me *ast.MatchExpression) {
+	p.write("match (")
+	p.expr(me.Subject, parser.LOWEST)
+	p.write(") {")
+	closeTkn := p.closeOf(p.matchBody(me))
+	if len(me.Arms) == 0 && !p.hasCommentsBefore(closeTkn) {
+		p.write("}")
+		return
+	}
+	p.newline()
+	p.indent++
+	p.fresh = true
+	for _, arm := range me.Arms {
+		p.leading(arm.Token)
+		p.pattern(arm.Pattern)
+		if arm.Guard != nil {
+			p.write(" if ")
+			// a bare arrow function would end the guard early
+			need := parser.LOWEST
+			if fl, ok := arm.Guard.(*ast.FunctionLiteral); ok && fl.Arrow {
+				need = atom
+			}
+			p.expr(arm.Guard, need)
+		}
+		p.write(" => ")
+		p.body(arm.Body)
+		p.write(",")
+		p.newline()
+	}
+	if closeTkn != nil {
+		p.flushComments(closeTkn)
+	}
+	p.indent--
+	p.write("}")
+}
+
+func (p *printer) pattern(pat ast.Pattern) {
+	switch pat := pat.(type) {
+	case *ast.WildcardPattern:
+		p.write("_")
+	case *ast.IdentifierPattern:
+		p.write(pat.Name.Value)
+	case *ast.LiteralPattern:
+		p.expr(pat.Value, parser.LOWEST)
+	case *ast.ArrayPattern:
+		p.write("[")
+		for i, e := range pat.Elements {
+			if i > 0 {
+				p.write(", ")
+			}
+			p.pattern(e)
+		}
+		if pat.Rest != nil {
+			if len(pat.Elements) > 0 {
+				p.write(", ")
+			}
+			p.write("..." + pat.Rest.Value)
+		}
+		p.write("]")
+	case *ast.HashPattern:
+		p.write("{")
+		for i, pair := range pat.Pairs {
+			if i > 0 {
+				p.write(", ")
+			}
+			if pair.Shorthand {
+				p.write(pair.Key.Value)
+				continue
+			}
+			p.write(pair.Key.Value + ": ")
+			p.pattern(pair.Value)
+		}
+		p.write("}")
+	default:
+		p.unsupported(pat)
+	}
+}
+
+func (p *printer) unsupported(node ast.Node) {
+	if p.err == nil {
+		p.err = errors.New(fmt.Sprintf("cannot format node %T", node))
+	}
+}
@@ -3,5 +3,9 @@ package lexer
 import "errors"
 
 var (
-	ErrBadVariableName = errors.New("bad variable name")
+	ErrBadVariableName    = errors.New("bad variable name")
+	ErrUnterminatedString = errors.New("unterminated string literal")
+	ErrBadEscape          = errors.New("unknown escape sequence in string literal")
+	ErrBadOperator        = errors.New("operator must be a word or made up of symbols only")
+	ErrOperatorExists     = errors.New("operator is already defined")
 )
@@ -1,6 +1,8 @@
 package lexer
 
 import (
+	"strings"
+
 	"github.com/geraldywy/monkey/logger"
 
 	"github.com/geraldywy/monkey/token"
@@ -15,14 +17,45 @@ type Lexer struct {
 	// metadata
 	FileName string
 	LineNum  int // line num is 1-indexed
-	LinePos  int // line position is 1-indexed
+	LinePos  int // line position of the prev char read in, 1-indexed
+
+	// ScanComments makes the lexer emit token.COMMENT tokens instead of skipping comments
+	ScanComments bool
+
+	// ErrorHandler, if set, is called with the position of each error found instead of the
+	// error being printed
+	ErrorHandler func(lineNum int, linePos int, err error)
+
+	// operators registered on this lexer on top of the ones in the token package
+	operators    map[string]token.TokenType
+	maxOperator  int
+	wordOperator map[string]token.TokenType
 }
 
 func New(input string, fileName string) *Lexer {
-	l := &Lexer{input: input, FileName: fileName, LineNum: 1, LinePos: 1}
+	l := &Lexer{input: input, FileName: fileName, LineNum: 1, LinePos: 0}
 	return l
 }
 
+// State is a snapshot of the lexer's read position, used by the parser to backtrack
+type State struct {
+	position int
+	ch       byte
+	lineNum  int
+	linePos  int
+}
+
+func (l *Lexer) Save() State {
+	return State{position: l.position, ch: l.ch, lineNum: l.LineNum, linePos: l.LinePos}
+}
+
+func (l *Lexer) Restore(s State) {
+	l.position = s.position
+	l.ch = s.ch
+	l.LineNum = s.lineNum
+	l.LinePos = s.linePos
+}
+
 func (l *Lexer) Debug() (string, int, string) {
 	return string(l.ch), l.position, "->" + string(l.input[l.position]) + "<-"
 }
@@ -35,6 +68,16 @@ func (l *Lexer) peekNext() byte {
 	return l.input[l.position]
 }
 
+// peekString returns up to the next n unread characters without consuming them
+func (l *Lexer) peekString(n int) string {
+	end := l.position + n
+	if end > len(l.input) {
+		end = len(l.input)
+	}
+
+	return l.input[l.position:end]
+}
+
 func (l *Lexer) readChar() {
 	if l.position == len(l.input) {
 		l.ch = 0
@@ -49,27 +92,47 @@ func (l *Lexer) readChar() {
 	l.LinePos++
 }
 
-func (l *Lexer) byte2Token(ch byte, isPeek bool) (*token.Token, error) {
-	startPos := l.position
+func (l *Lexer) byte2Token(ch byte, isPeek bool) (tkn *token.Token, err error) {
+	start := l.Save()
 	defer func() {
 		// restore for peeks
 		if isPeek {
-			l.position = startPos
+			l.Restore(start)
 		}
 	}()
 
 	l.readChar()
-	if tt, exist := token.SingleToken[ch]; exist {
-		// special case for double tokens
-		cand := string(ch) + string(l.peekNext())
-		if dblTt, candExist := token.DoubleToken[cand]; candExist {
-			l.readChar()
-			return newToken(dblTt, cand), nil
+	line, col := l.LineNum, l.LinePos
+	defer func() {
+		if tkn != nil {
+			tkn.Line, tkn.Column = line, col
 		}
-
-		return newToken(tt, string(ch)), nil
-	} else if ch == 0 {
+	}()
+	if ch == '/' && l.peekNext() == '/' {
+		return newToken(token.COMMENT, l.readComment()), nil
+	}
+	// longest match first, triple tokens take priority over double tokens, which take
+	// priority over single tokens
+	for n := l.maxOperatorLen(); n > 0; n-- {
+		cand := string(ch) + l.peekString(n-1)
+		if len(cand) != n {
+			continue
+		}
+		if tt, candExist := l.lookupOperator(cand); candExist {
+			for i := 1; i < n; i++ {
+				l.readChar()
+			}
+			return newToken(tt, cand), nil
+		}
+	}
+	if ch == 0 {
 		return newToken(token.EOF, ""), nil
+	} else if ch == '"' {
+		literal, err := l.readStringLiteral()
+		if err != nil {
+			return nil, err
+		}
+		return newToken(token.STRING, literal), nil
 	}
 
 	// handle all keywords/identifiers/numbers (really, just integers)
@@ -78,6 +141,9 @@ func (l *Lexer) byte2Token(ch byte, isPeek bool) (*token.Token, error) {
 		if err != nil {
 			return nil, err
 		}
+		if tt, exist := l.wordOperator[literal]; exist {
+			return newToken(tt, literal), nil
+		}
 		return &token.Token{
 			Type:    token.LookupTType(literal),
 			Literal: literal,
@@ -87,6 +153,73 @@ func (l *Lexer) byte2Token(ch byte, isPeek bool) (*token.Token, error) {
 	return newToken(token.ILLEGAL, string(ch)), nil
 }
 
+// RegisterOperator makes the lexer emit a token of type tt for literal. literal is either
+// made up entirely of symbols, e.g. "<=>", or is a word, e.g. "in", which then stops being
+// usable as an identifier. Symbol operators take part in the usual longest match.
+func (l *Lexer) RegisterOperator(literal string, tt token.TokenType) error {
+	if literal == "" || tt == "" {
+		return ErrBadOperator
+	}
+	if isWord(literal) {
+		if token.LookupTType(literal) != token.IDENT || l.wordOperator[literal] != "" {
+			return ErrOperatorExists
+		}
+		if l.wordOperator == nil {
+			l.wordOperator = make(map[string]token.TokenType)
+		}
+		l.wordOperator[literal] = tt
+		return nil
+	}
+
+	for i := 0; i < len(literal); i++ {
+		if !isSymbol(literal[i]) {
+			return ErrBadOperator
+		}
+	}
+	if strings.Contains(literal, "//") {
+		// would start a comment
+		return ErrBadOperator
+	}
+	if _, exist := l.lookupOperator(literal); exist {
+		return ErrOperatorExists
+	}
+	if l.operators == nil {
+		l.operators = make(map[string]token.TokenType)
+	}
+	l.operators[literal] = tt
+	if len(literal) > l.maxOperator {
+		l.maxOperator = len(literal)
+	}
+
+	return nil
+}
+
+func (l *Lexer) lookupOperator(literal string) (token.TokenType, bool) {
+	if tt, exist := l.operators[literal]; exist {
+		return tt, true
+	}
+	var tt token.TokenType
+	var exist bool
+	switch len(literal) {
+	case 1:
+		tt, exist = token.SingleToken[literal[0]]
+	case 2:
+		tt, exist = token.DoubleToken[literal]
+	case 3:
+		tt, exist = token.TripleToken[literal]
+	}
+
+	return tt, exist
+}
+
+func (l *Lexer) maxOperatorLen() int {
+	if l.maxOperator > 3 {
+		return l.maxOperator
+	}
+
+	return 3
+}
+
 func (l *Lexer) NextToken() (*token.Token, error) {
 	l.eatWhitespaces()
 	return l.byte2Token(l.peekNext(), false)
@@ -98,9 +231,38 @@ func (l *Lexer) PeekToken() (*token.Token, error) {
 }
 
 func (l *Lexer) eatWhitespaces() {
-	for utils.IsWhitespace(l.peekNext()) {
+	for {
+		for utils.IsWhitespace(l.peekNext()) {
+			l.readChar()
+		}
+		if l.ScanComments || l.peekString(2) != "//" {
+			return
+		}
+		l.readChar()
+		l.readComment()
+	}
+}
+
+// readComment reads the rest of a comment whose leading '/' was just read in, leaving the
+// newline ending it unread. The comment is returned without trailing whitespace.
+func (l *Lexer) readComment() string {
+	start := l.position - 1
+	for l.peekNext() != '\n' && l.peekNext() != 0 {
 		l.readChar()
 	}
+
+	return strings.TrimRight(l.input[start:l.position], " \t\r")
+}
+
+// error reports err at the current position and returns it
+func (l *Lexer) error(err error) error {
+	if l.ErrorHandler != nil {
+		l.ErrorHandler(l.LineNum, l.LinePos, err)
+	} else {
+		logger.PrettyPrintErr(l.FileName, l.LineNum, l.LinePos, err)
+	}
+
+	return err
 }
 
 func (l *Lexer) readIdentLiteral() (string, error) {
@@ -111,8 +273,7 @@ func (l *Lexer) readIdentLiteral() (string, error) {
 		}
 		if utils.IsAlphaOrUnderscore(l.peekNext()) {
 			// a variable cannot start with a number
-			logger.PrettyPrintErr(l.FileName, l.LineNum, l.LinePos, ErrBadVariableName)
-			return "", ErrBadVariableName
+			return "", l.error(ErrBadVariableName)
 		}
 	} else {
 		for utils.IsAlphaOrUnderscore(l.peekNext()) || utils.IsDigit(l.peekNext()) {
@@ -123,6 +284,60 @@ func (l *Lexer) readIdentLiteral() (string, error) {
 	return l.input[start:l.position], nil
 }
 
+// readStringLiteral reads up to and including the closing '"', returning the unescaped contents
+func (l *Lexer) readStringLiteral() (string, error) {
+	var sb strings.Builder
+	for {
+		if l.peekNext() == 0 {
+			return "", l.error(ErrUnterminatedString)
+		}
+		l.readChar()
+		switch l.ch {
+		case '"':
+			return sb.String(), nil
+		case '\\':
+			if l.peekNext() == 0 {
+				return "", l.error(ErrUnterminatedString)
+			}
+			l.readChar()
+			esc, ok := escapes[l.ch]
+			if !ok {
+				return "", l.error(ErrBadEscape)
+			}
+			sb.WriteByte(esc)
+		default:
+			sb.WriteByte(l.ch)
+		}
+	}
+}
+
+var escapes = map[byte]byte{
+	'"':  '"',
+	'\\': '\\',
+	'n':  '\n',
+	't':  '\t',
+	'r':  '\r',
+}
+
+func isWord(s string) bool {
+	if !utils.IsAlphaOrUnderscore(s[0]) {
+		return false
+	}
+	for i := 1; i < len(s); i++ {
+		if !isSupportedChar(s[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isSymbol reports whether ch may appear in a registered operator, anything printable that
+// cannot start an identifier, number or string
+func isSymbol(ch byte) bool {
+	return ch > ' ' && ch < 0x7f && !isSupportedChar(ch) && ch != '"'
+}
+
 func isSupportedChar(ch byte) bool {
 	return utils.IsDigit(ch) || utils.IsAlphaOrUnderscore(ch)
 }
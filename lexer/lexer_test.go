@@ -199,6 +199,130 @@ func TestNextToken(t *testing.T) {
 				{token.EOF, "", nil},
 			},
 		},
+		{
+			name: "type annotations",
+			in:   "fn(a: int) -> int {} a->b",
+			wants: []tsWants{
+				{token.FUNCTION, "fn", nil},
+				{token.LPAREN, "(", nil},
+				{token.IDENT, "a", nil},
+				{token.COLON, ":", nil},
+				{token.IDENT, "int", nil},
+				{token.RPAREN, ")", nil},
+				{token.THINARROW, "->", nil},
+				{token.IDENT, "int", nil},
+				{token.LBRACE, "{", nil},
+				{token.RBRACE, "}", nil},
+				{token.IDENT, "a", nil},
+				{token.THINARROW, "->", nil},
+				{token.IDENT, "b", nil},
+				{token.EOF, "", nil},
+			},
+		},
+		{
+			name: "match expression symbols",
+			in:   "match (x) { [h, ...t] => h, {a: b} => b, _ => 0 }",
+			wants: []tsWants{
+				{token.MATCH, "match", nil},
+				{token.LPAREN, "(", nil},
+				{token.IDENT, "x", nil},
+				{token.RPAREN, ")", nil},
+				{token.LBRACE, "{", nil},
+				{token.LBRACKET, "[", nil},
+				{token.IDENT, "h", nil},
+				{token.COMMA, ",", nil},
+				{token.ELLIPSIS, "...", nil},
+				{token.IDENT, "t", nil},
+				{token.RBRACKET, "]", nil},
+				{token.ARROW, "=>", nil},
+				{token.IDENT, "h", nil},
+				{token.COMMA, ",", nil},
+				{token.LBRACE, "{", nil},
+				{token.IDENT, "a", nil},
+				{token.COLON, ":", nil},
+				{token.IDENT, "b", nil},
+				{token.RBRACE, "}", nil},
+				{token.ARROW, "=>", nil},
+				{token.IDENT, "b", nil},
+				{token.COMMA, ",", nil},
+				{token.IDENT, "_", nil},
+				{token.ARROW, "=>", nil},
+				{token.INT, "0", nil},
+				{token.RBRACE, "}", nil},
+				{token.EOF, "", nil},
+			},
+		},
+		{
+			name: "null and optional chaining",
+			in:   "a ?? null; a?.b?[0]; a.b[1]; ?",
+			wants: []tsWants{
+				{token.IDENT, "a", nil},
+				{token.NULLISH, "??", nil},
+				{token.NULL, "null", nil},
+				{token.SEMICOLON, ";", nil},
+				{token.IDENT, "a", nil},
+				{token.OPTDOT, "?.", nil},
+				{token.IDENT, "b", nil},
+				{token.OPTLBRACK, "?[", nil},
+				{token.INT, "0", nil},
+				{token.RBRACKET, "]", nil},
+				{token.SEMICOLON, ";", nil},
+				{token.IDENT, "a", nil},
+				{token.DOT, ".", nil},
+				{token.IDENT, "b", nil},
+				{token.LBRACKET, "[", nil},
+				{token.INT, "1", nil},
+				{token.RBRACKET, "]", nil},
+				{token.SEMICOLON, ";", nil},
+				{token.ILLEGAL, "?", nil},
+				{token.EOF, "", nil},
+			},
+		},
+		{
+			name: "arithmetic and bitwise operators",
+			in:   "a % b ** c & d | e ^ ~f << g >> h <= i",
+			wants: []tsWants{
+				{token.IDENT, "a", nil},
+				{token.PERCENT, "%", nil},
+				{token.IDENT, "b", nil},
+				{token.POWER, "**", nil},
+				{token.IDENT, "c", nil},
+				{token.AMPERSAND, "&", nil},
+				{token.IDENT, "d", nil},
+				{token.PIPE, "|", nil},
+				{token.IDENT, "e", nil},
+				{token.CARET, "^", nil},
+				{token.TILDE, "~", nil},
+				{token.IDENT, "f", nil},
+				{token.LSHIFT, "<<", nil},
+				{token.IDENT, "g", nil},
+				{token.RSHIFT, ">>", nil},
+				{token.IDENT, "h", nil},
+				{token.LTE, "<=", nil},
+				{token.IDENT, "i", nil},
+				{token.EOF, "", nil},
+			},
+		},
+		{
+			name: "strings and modules",
+			in:   `import "lib/math.mk" as m; "a\tb\"c"`,
+			wants: []tsWants{
+				{token.IMPORT, "import", nil},
+				{token.STRING, "lib/math.mk", nil},
+				{token.AS, "as", nil},
+				{token.IDENT, "m", nil},
+				{token.SEMICOLON, ";", nil},
+				{token.STRING, "a\tb\"c", nil},
+				{token.EOF, "", nil},
+			},
+		},
+		{
+			name: "unterminated string",
+			in:   `"abc`,
+			wants: []tsWants{
+				{"", "", lexer.ErrUnterminatedString},
+			},
+		},
 	}
 
 	for _, ts := range tests {
@@ -210,6 +334,9 @@ func TestNextToken(t *testing.T) {
 				t.Fatalf("test name: %s, tests[%d] - next token err mismatch. expected=%q, got=%q",
 					ts.name, i, tw.wantErr, err)
 			}
+			if tw.wantErr != nil {
+				continue
+			}
 
 			if tok.Type != tw.wantType {
 				t.Fatalf("test name: %s, tests[%d] - tokentype wrong. expected=%q, got=%q",
@@ -223,3 +350,152 @@ func TestNextToken(t *testing.T) {
 		}
 	}
 }
+
+func TestTokenPositions(t *testing.T) {
+	in := "let x = 5;\n  add(x,\n\ty);"
+	wants := []struct {
+		literal string
+		line    int
+		column  int
+	}{
+		{"let", 1, 1},
+		{"x", 1, 5},
+		{"=", 1, 7},
+		{"5", 1, 9},
+		{";", 1, 10},
+		{"add", 2, 3},
+		{"(", 2, 6},
+		{"x", 2, 7},
+		{",", 2, 8},
+		{"y", 3, 2},
+		{")", 3, 3},
+		{";", 3, 4},
+	}
+
+	l := lexer.New(in, "lexer_test.go")
+	for i, want := range wants {
+		// peeking must not disturb the position of the next token
+		if _, err := l.PeekToken(); err != nil {
+			t.Fatalf("tests[%d] - unexpected peek error: %s", i, err)
+		}
+		tok, err := l.NextToken()
+		if err != nil {
+			t.Fatalf("tests[%d] - unexpected error: %s", i, err)
+		}
+		if tok.Literal != want.literal || tok.Line != want.line || tok.Column != want.column {
+			t.Fatalf("tests[%d] - token wrong. expected=%q at %d:%d, got=%q at %d:%d",
+				i, want.literal, want.line, want.column, tok.Literal, tok.Line, tok.Column)
+		}
+	}
+}
+
+func TestRegisterOperator(t *testing.T) {
+	l := lexer.New("a <=> b =~ c in d <= e ~~~~ f", "lexer_test.go")
+	for literal, tt := range map[string]token.TokenType{
+		"<=>":  "SPACESHIP",
+		"=~":   "MATCHES",
+		"in":   "IN",
+		"~~~~": "SQUIGGLE",
+	} {
+		if err := l.RegisterOperator(literal, tt); err != nil {
+			t.Fatalf("unexpected error registering %q: %s", literal, err)
+		}
+	}
+	wants := []tsWants{
+		{wantType: token.IDENT, wantLiteral: "a"},
+		{wantType: "SPACESHIP", wantLiteral: "<=>"},
+		{wantType: token.IDENT, wantLiteral: "b"},
+		{wantType: "MATCHES", wantLiteral: "=~"},
+		{wantType: token.IDENT, wantLiteral: "c"},
+		{wantType: "IN", wantLiteral: "in"},
+		{wantType: token.IDENT, wantLiteral: "d"},
+		{wantType: token.LTE, wantLiteral: "<="},
+		{wantType: token.IDENT, wantLiteral: "e"},
+		{wantType: "SQUIGGLE", wantLiteral: "~~~~"},
+		{wantType: token.IDENT, wantLiteral: "f"},
+		{wantType: token.EOF, wantLiteral: ""},
+	}
+	for i, want := range wants {
+		tok, err := l.NextToken()
+		if err != nil {
+			t.Fatalf("tests[%d] - unexpected error: %s", i, err)
+		}
+		if tok.Type != want.wantType || tok.Literal != want.wantLiteral {
+			t.Fatalf("tests[%d] - token wrong. expected=%q %q, got=%q %q",
+				i, want.wantType, want.wantLiteral, tok.Type, tok.Literal)
+		}
+	}
+
+	// operators are registered per lexer
+	other := lexer.New("in", "lexer_test.go")
+	if tok, _ := other.NextToken(); tok.Type != token.IDENT {
+		t.Errorf("expected in to be an identifier on a fresh lexer, got=%q", tok.Type)
+	}
+}
+
+func TestRegisterOperatorErrors(t *testing.T) {
+	tests := []struct {
+		literal string
+		wantErr error
+	}{
+		{"", lexer.ErrBadOperator},
+		{"<a", lexer.ErrBadOperator},
+		{"1x", lexer.ErrBadOperator},
+		{"a b", lexer.ErrBadOperator},
+		{"//", lexer.ErrBadOperator},
+		{"==", lexer.ErrOperatorExists},
+		{"if", lexer.ErrOperatorExists},
+	}
+	for _, tt := range tests {
+		l := lexer.New("", "lexer_test.go")
+		if err := l.RegisterOperator(tt.literal, "OP"); err != tt.wantErr {
+			t.Errorf("RegisterOperator(%q) - expected error %v, got=%v", tt.literal, tt.wantErr, err)
+		}
+	}
+}
+
+func TestComments(t *testing.T) {
+	in := "// leading\nlet x = 10 / 2; // trailing  \n// end"
+	skipped := []tsWants{
+		{wantType: token.LET, wantLiteral: "let"},
+		{wantType: token.IDENT, wantLiteral: "x"},
+		{wantType: token.ASSIGN, wantLiteral: "="},
+		{wantType: token.INT, wantLiteral: "10"},
+		{wantType: token.SLASH, wantLiteral: "/"},
+		{wantType: token.INT, wantLiteral: "2"},
+		{wantType: token.SEMICOLON, wantLiteral: ";"},
+		{wantType: token.EOF, wantLiteral: ""},
+	}
+	scanned := []tsWants{
+		{wantType: token.COMMENT, wantLiteral: "// leading"},
+		{wantType: token.LET, wantLiteral: "let"},
+		{wantType: token.IDENT, wantLiteral: "x"},
+		{wantType: token.ASSIGN, wantLiteral: "="},
+		{wantType: token.INT, wantLiteral: "10"},
+		{wantType: token.SLASH, wantLiteral: "/"},
+		{wantType: token.INT, wantLiteral: "2"},
+		{wantType: token.SEMICOLON, wantLiteral: ";"},
+		{wantType: token.COMMENT, wantLiteral: "// trailing"},
+		{wantType: token.COMMENT, wantLiteral: "// end"},
+		{wantType: token.EOF, wantLiteral: ""},
+	}
+
+	for _, scan := range []bool{false, true} {
+		l := lexer.New(in, "lexer_test.go")
+		l.ScanComments = scan
+		wants := skipped
+		if scan {
+			wants = scanned
+		}
+		for i, want := range wants {
+			tok, err := l.NextToken()
+			if err != nil {
+				t.Fatalf("scan=%t tests[%d] - unexpected error: %s", scan, i, err)
+			}
+			if tok.Type != want.wantType || tok.Literal != want.wantLiteral {
+				t.Fatalf("scan=%t tests[%d] - token wrong. expected=%q %q, got=%q %q",
+					scan, i, want.wantType, want.wantLiteral, tok.Type, tok.Literal)
+			}
+		}
+	}
+}
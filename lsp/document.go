@@ -0,0 +1,361 @@
+package lsp
+
+import (
+	"errors"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/geraldywy/monkey/ast"
+	"github.com/geraldywy/monkey/lexer"
+	"github.com/geraldywy/monkey/parser"
+	"github.com/geraldywy/monkey/resolver"
+	"github.com/geraldywy/monkey/token"
+	"github.com/geraldywy/monkey/types"
+)
+
+// document is an open text document, analysed each time its text changes
+type document struct {
+	uri         string
+	lines       []string     // the text, used to convert byte columns into UTF-16 ones
+	program     *ast.Program // the statements that parsed
+	diagnostics []Diagnostic
+	inference   *types.Inference
+	// decls maps the identifier a let or import declares to its statement
+	decls map[*ast.Identifier]ast.Statement
+}
+
+// errorPos matches the position parser errors are prefixed with
+var errorPos = regexp.MustCompile(`line: (\d+) col: (\d+) `)
+
+// lexerErrors are reported through the lexer's ErrorHandler, along with their position
+var lexerErrors = []error{lexer.ErrBadVariableName, lexer.ErrUnterminatedString, lexer.ErrBadEscape}
+
+func newDocument(uri, text string) *document {
+	d := &document{uri: uri, lines: strings.Split(text, "\n"), decls: make(map[*ast.Identifier]ast.Statement)}
+	l := lexer.New(text, fileName(uri))
+	// the parser backtracks, so the same lexer error may be found more than once
+	seen := make(map[Diagnostic]bool)
+	l.ErrorHandler = func(lineNum int, linePos int, err error) {
+		diag := d.diagnostic(lineNum, linePos, err.Error())
+		if !seen[diag] {
+			seen[diag] = true
+			d.diagnostics = append(d.diagnostics, diag)
+		}
+	}
+	p := parser.New(l)
+	d.program = p.ParseProgram()
+	for _, err := range p.Errors {
+		if isLexerError(err) {
+			continue
+		}
+		msg := err.Error()
+		if loc := errorPos.FindStringSubmatchIndex(msg); loc != nil {
+			line, _ := strconv.Atoi(msg[loc[2]:loc[3]])
+			col, _ := strconv.Atoi(msg[loc[4]:loc[5]])
+			d.diagnostics = append(d.diagnostics, d.diagnostic(line, col, msg[loc[1]:]))
+		} else {
+			// the error has no position, the lexer stopped where it was found
+			d.diagnostics = append(d.diagnostics, d.diagnostic(l.LineNum, l.LinePos, msg))
+		}
+	}
+	sort.SliceStable(d.diagnostics, func(i, j int) bool {
+		return before(d.diagnostics[i].Range.Start, d.diagnostics[j].Range.Start)
+	})
+
+	// the inference's symbol table is the one its types are keyed by, type errors are not
+	// reported as the program need not be annotated
+	d.inference, _ = types.Infer(d.program, fileName(uri))
+	ast.Inspect(d.program, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.LetStatement:
+			if n.Pattern == nil {
+				d.decls[n.Name] = n
+				break
+			}
+			for _, ident := range ast.Bindings(n.Pattern) {
+				d.decls[ident] = n
+			}
+		case *ast.ImportStatement:
+			d.decls[n.Alias] = n
+		}
+		return true
+	})
+
+	return d
+}
+
+// fileName returns the path of a file URI, used in error messages
+func fileName(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return uri
+	}
+
+	return u.Path
+}
+
+func isLexerError(err error) bool {
+	for _, target := range lexerErrors {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// diagnostic returns an error diagnostic for the character at the 1-indexed line and col
+func (d *document) diagnostic(line, col int, msg string) Diagnostic {
+	if col < 1 {
+		col = 1
+	}
+	return Diagnostic{
+		Range:    Range{Start: d.position(line, col), End: d.position(line, col+1)},
+		Severity: SeverityError,
+		Source:   "monkey",
+		Message:  msg,
+	}
+}
+
+// position converts a 1-indexed line and byte column of the text into a Position, whose
+// character counts UTF-16 code units as LSP clients expect
+func (d *document) position(line, col int) Position {
+	pos := Position{Line: line - 1, Character: col - 1}
+	if pos.Line < 0 || pos.Line >= len(d.lines) || pos.Character <= 0 {
+		return pos
+	}
+	text := d.lines[pos.Line]
+	n := pos.Character
+	if n > len(text) {
+		// past the end of the line, e.g. an error at the end of the file
+		n = len(text)
+	}
+	pos.Character = len(utf16.Encode([]rune(text[:n]))) + pos.Character - n
+
+	return pos
+}
+
+func before(a, b Position) bool {
+	if a.Line != b.Line {
+		return a.Line < b.Line
+	}
+	return a.Character < b.Character
+}
+
+// identRange returns the range of the source ident was parsed from
+func (d *document) identRange(ident *ast.Identifier) Range {
+	return Range{
+		Start: d.position(ident.Token.Line, ident.Token.Column),
+		End:   d.position(ident.Token.Line, ident.Token.Column+len(ident.Value)),
+	}
+}
+
+func contains(r Range, pos Position) bool {
+	return !before(pos, r.Start) && !before(r.End, pos)
+}
+
+// identAt returns the identifier at pos along with its symbol, nil if there is no resolved
+// identifier there
+func (d *document) identAt(pos Position) (*ast.Identifier, *resolver.Symbol) {
+	info := d.inference.Symbols
+	for _, idents := range []map[*ast.Identifier]*resolver.Symbol{info.Defs, info.Uses} {
+		for ident, sym := range idents {
+			if contains(d.identRange(ident), pos) {
+				return ident, sym
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+// describe returns the declaration of sym as Monkey source, with function bodies left out
+func (d *document) describe(sym *resolver.Symbol) string {
+	switch sym.Kind {
+	case resolver.Predeclared:
+		return "(predeclared) " + sym.Name
+	case resolver.Param:
+		if fn, ok := sym.Scope.Node.(*ast.FunctionLiteral); ok {
+			for i, p := range fn.Parameters {
+				if p == sym.Decl && fn.ParamType(i) != nil {
+					return "(parameter) " + sym.Name + ": " + fn.ParamType(i).String()
+				}
+			}
+		}
+		return "(parameter) " + sym.Name
+	case resolver.Match:
+		return "(match binding) " + sym.Name
+	case resolver.Catch:
+		return "(catch binding) " + sym.Name
+	}
+
+	var out strings.Builder
+	if sym.Exported {
+		out.WriteString("export ")
+	}
+	switch s := d.decls[sym.Decl].(type) {
+	case *ast.ImportStatement:
+		out.WriteString(s.String())
+	case *ast.LetStatement:
+		if s.Pattern != nil {
+			out.WriteString(s.String())
+			break
+		}
+		out.WriteString("let " + sym.Name)
+		if s.Type != nil {
+			out.WriteString(": " + s.Type.String())
+		}
+		if fn, ok := s.Value.(*ast.FunctionLiteral); ok {
+			out.WriteString(" = fn" + fn.Signature())
+		} else if s.Value != nil {
+			out.WriteString(" = " + s.Value.String() + ";")
+		}
+	default:
+		out.WriteString("let " + sym.Name)
+	}
+
+	return out.String()
+}
+
+// hover returns the markdown shown for sym, its declaration and inferred type
+func (d *document) hover(sym *resolver.Symbol) string {
+	var out strings.Builder
+	out.WriteString("```monkey\n")
+	out.WriteString(d.describe(sym))
+	out.WriteString("\n```")
+	if scheme, ok := d.inference.Defs[sym]; ok {
+		out.WriteString("\n\n`" + sym.Name + ": " + scheme.String() + "`")
+	}
+
+	return out.String()
+}
+
+// references returns the locations of the uses of sym, along with its declaration if decl
+// is set, in source order
+func (d *document) references(sym *resolver.Symbol, decl bool) []Location {
+	idents := append([]*ast.Identifier{}, sym.Uses...)
+	if decl && sym.Decl != nil {
+		idents = append(idents, sym.Decl)
+	}
+	sort.Slice(idents, func(i, j int) bool {
+		return before(d.identRange(idents[i]).Start, d.identRange(idents[j]).Start)
+	})
+	locs := make([]Location, 0, len(idents))
+	for _, ident := range idents {
+		locs = append(locs, Location{URI: d.uri, Range: d.identRange(ident)})
+	}
+
+	return locs
+}
+
+// symbols returns the names declared by the top level lets of the document
+func (d *document) symbols() []DocumentSymbol {
+	syms := []DocumentSymbol{}
+	global := d.inference.Symbols.Scopes[d.program]
+	if global == nil {
+		return syms
+	}
+	for _, sym := range global.Symbols {
+		if sym.Kind != resolver.Let {
+			continue
+		}
+		ds := DocumentSymbol{
+			Name:           sym.Name,
+			Kind:           SymbolVariable,
+			Range:          d.identRange(sym.Decl),
+			SelectionRange: d.identRange(sym.Decl),
+		}
+		if fn, ok := sym.Value.(*ast.FunctionLiteral); ok {
+			ds.Kind = SymbolFunction
+			ds.Detail = "fn" + fn.Signature()
+		}
+		if ls, ok := d.decls[sym.Decl].(*ast.LetStatement); ok {
+			ds.Range.Start = d.position(ls.Token.Line, ls.Token.Column)
+		}
+		syms = append(syms, ds)
+	}
+
+	return syms
+}
+
+// completions returns the keywords, followed by the names visible at pos
+func (d *document) completions(pos Position) []CompletionItem {
+	items := []CompletionItem{}
+	for _, kw := range token.Keywords() {
+		items = append(items, CompletionItem{Label: kw, Kind: CompletionKeyword})
+	}
+	seen := make(map[string]bool)
+	for s := d.scopeAt(pos); s != nil; s = s.Parent {
+		// later declarations of a name shadow earlier ones
+		for i := len(s.Symbols) - 1; i >= 0; i-- {
+			sym := s.Symbols[i]
+			if seen[sym.Name] || sym.Name == "_" {
+				continue
+			}
+			seen[sym.Name] = true
+			item := CompletionItem{Label: sym.Name, Kind: CompletionVariable, Detail: d.describe(sym)}
+			if scheme, ok := d.inference.Defs[sym]; ok {
+				item.Detail = scheme.String()
+			}
+			if _, ok := sym.Value.(*ast.FunctionLiteral); ok {
+				item.Kind = CompletionFunction
+			}
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+// scopeAt returns the innermost scope whose node spans pos
+func (d *document) scopeAt(pos Position) *resolver.Scope {
+	scope := d.inference.Symbols.Scopes[d.program]
+	if scope == nil {
+		return d.inference.Symbols.Universe
+	}
+	for found := true; found; {
+		found = false
+		for _, child := range scope.Children {
+			if contains(d.extent(child.Node), pos) {
+				scope, found = child, true
+				break
+			}
+		}
+	}
+
+	return scope
+}
+
+// extent returns the range from the first to the end of the last token of node. A block
+// ends where its closing brace starts, so a position just before the brace is inside it.
+func (d *document) extent(node ast.Node) Range {
+	var r Range
+	first := true
+	extend := func(start, end Position) {
+		if first || before(start, r.Start) {
+			r.Start = start
+		}
+		if first || before(r.End, end) {
+			r.End = end
+		}
+		first = false
+	}
+	ast.Inspect(node, func(n ast.Node) bool {
+		if block, ok := n.(*ast.BlockStatement); ok && block.Rbrace != nil {
+			rbrace := d.position(block.Rbrace.Line, block.Rbrace.Column)
+			extend(rbrace, rbrace)
+		}
+		tkn := ast.TokenOf(n)
+		if tkn == nil || tkn.Line == 0 {
+			return n != nil
+		}
+		extend(d.position(tkn.Line, tkn.Column), d.position(tkn.Line, tkn.Column+len(tkn.Literal)))
+		return true
+	})
+
+	return r
+}
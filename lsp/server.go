@@ -0,0 +1,225 @@
+// Package lsp implements a Language Server Protocol server for Monkey, giving editors
+// diagnostics, hover, go to definition, find references, document symbols and completion.
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Server serves a single client, over a stream of JSON-RPC messages
+type Server struct {
+	in  *bufio.Reader
+	out io.Writer
+
+	docs     map[string]*document // the open documents, by URI
+	shutdown bool
+}
+
+func NewServer(in io.Reader, out io.Writer) *Server {
+	return &Server{in: bufio.NewReader(in), out: out, docs: make(map[string]*document)}
+}
+
+// ErrNoShutdown is returned by Serve if the client exits without asking the server to shut
+// down first
+var ErrNoShutdown = errors.New("exit before shutdown")
+
+// Serve handles messages until the client exits or closes the input stream
+func (s *Server) Serve() error {
+	for {
+		content, err := readMessage(s.in)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		var req request
+		if err := json.Unmarshal(content, &req); err != nil {
+			if err := s.reply(nil, nil, &rpcError{Code: codeParseError, Message: err.Error()}); err != nil {
+				return err
+			}
+			continue
+		}
+		if req.Method == "exit" {
+			if !s.shutdown {
+				return ErrNoShutdown
+			}
+			return nil
+		}
+
+		result, rerr := s.handle(&req)
+		if req.ID == nil { // notifications are not replied to
+			continue
+		}
+		if err := s.reply(req.ID, result, rerr); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *Server) reply(id *json.RawMessage, result interface{}, rerr *rpcError) error {
+	resp := response{JSONRPC: "2.0", ID: id, Error: rerr}
+	if rerr == nil {
+		raw, err := json.Marshal(result)
+		if err != nil {
+			return err
+		}
+		resp.Result = raw
+	}
+
+	return writeMessage(s.out, resp)
+}
+
+func (s *Server) notify(method string, params interface{}) error {
+	return writeMessage(s.out, notification{JSONRPC: "2.0", Method: method, Params: params})
+}
+
+// handle dispatches req, the result is ignored for notifications
+func (s *Server) handle(req *request) (interface{}, *rpcError) {
+	if s.shutdown && req.Method != "exit" {
+		return nil, &rpcError{Code: codeInvalidRequest, Message: "server is shut down"}
+	}
+
+	switch req.Method {
+	case "initialize":
+		var result InitializeResult
+		result.Capabilities = ServerCapabilities{
+			TextDocumentSync:       TextDocumentSyncFull,
+			HoverProvider:          true,
+			DefinitionProvider:     true,
+			ReferencesProvider:     true,
+			DocumentSymbolProvider: true,
+		}
+		result.ServerInfo.Name = "monkey"
+		return result, nil
+	case "initialized", "$/cancelRequest", "$/setTrace", "workspace/didChangeConfiguration":
+		return nil, nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+
+	case "textDocument/didOpen":
+		var params DidOpenTextDocumentParams
+		if err := unmarshal(req.Params, &params); err != nil {
+			return nil, err
+		}
+		return nil, s.update(params.TextDocument.URI, params.TextDocument.Text)
+	case "textDocument/didChange":
+		var params DidChangeTextDocumentParams
+		if err := unmarshal(req.Params, &params); err != nil {
+			return nil, err
+		}
+		if len(params.ContentChanges) == 0 {
+			return nil, nil
+		}
+		// with full sync the last change holds the whole document
+		text := params.ContentChanges[len(params.ContentChanges)-1].Text
+		return nil, s.update(params.TextDocument.URI, text)
+	case "textDocument/didClose":
+		var params DidCloseTextDocumentParams
+		if err := unmarshal(req.Params, &params); err != nil {
+			return nil, err
+		}
+		delete(s.docs, params.TextDocument.URI)
+		// clear the diagnostics of the closed document
+		return nil, s.publish(params.TextDocument.URI, []Diagnostic{})
+
+	case "textDocument/hover":
+		var params TextDocumentPositionParams
+		doc, err := s.document(req.Params, &params, &params)
+		if err != nil || doc == nil {
+			return nil, err
+		}
+		ident, sym := doc.identAt(params.Position)
+		if ident == nil {
+			return nil, nil
+		}
+		return Hover{
+			Contents: MarkupContent{Kind: "markdown", Value: doc.hover(sym)},
+			Range:    doc.identRange(ident),
+		}, nil
+	case "textDocument/definition":
+		var params TextDocumentPositionParams
+		doc, err := s.document(req.Params, &params, &params)
+		if err != nil || doc == nil {
+			return nil, err
+		}
+		ident, sym := doc.identAt(params.Position)
+		if ident == nil || sym.Decl == nil {
+			return nil, nil
+		}
+		return Location{URI: doc.uri, Range: doc.identRange(sym.Decl)}, nil
+	case "textDocument/references":
+		var params ReferenceParams
+		doc, err := s.document(req.Params, &params, &params.TextDocumentPositionParams)
+		if err != nil || doc == nil {
+			return nil, err
+		}
+		ident, sym := doc.identAt(params.Position)
+		if ident == nil {
+			return nil, nil
+		}
+		return doc.references(sym, params.Context.IncludeDeclaration), nil
+	case "textDocument/documentSymbol":
+		var params DocumentSymbolParams
+		if err := unmarshal(req.Params, &params); err != nil {
+			return nil, err
+		}
+		doc, ok := s.docs[params.TextDocument.URI]
+		if !ok {
+			return nil, nil
+		}
+		return doc.symbols(), nil
+	case "textDocument/completion":
+		var params TextDocumentPositionParams
+		doc, err := s.document(req.Params, &params, &params)
+		if err != nil || doc == nil {
+			return nil, err
+		}
+		return doc.completions(params.Position), nil
+	}
+
+	return nil, &rpcError{Code: codeMethodNotFound, Message: fmt.Sprintf("method not found: %s", req.Method)}
+}
+
+func unmarshal(raw json.RawMessage, v interface{}) *rpcError {
+	if err := json.Unmarshal(raw, v); err != nil {
+		return &rpcError{Code: codeInvalidParams, Message: err.Error()}
+	}
+
+	return nil
+}
+
+// document decodes raw into params and returns the open document pos refers to, nil if it
+// is not open
+func (s *Server) document(raw json.RawMessage, params interface{}, pos *TextDocumentPositionParams) (*document, *rpcError) {
+	if err := unmarshal(raw, params); err != nil {
+		return nil, err
+	}
+
+	return s.docs[pos.TextDocument.URI], nil
+}
+
+// update analyses the new text of a document and publishes its diagnostics
+func (s *Server) update(uri, text string) *rpcError {
+	doc := newDocument(uri, text)
+	s.docs[uri] = doc
+	diags := doc.diagnostics
+	if diags == nil {
+		diags = []Diagnostic{}
+	}
+
+	return s.publish(uri, diags)
+}
+
+func (s *Server) publish(uri string, diags []Diagnostic) *rpcError {
+	if err := s.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{URI: uri, Diagnostics: diags}); err != nil {
+		return &rpcError{Code: codeInvalidRequest, Message: err.Error()}
+	}
+
+	return nil
+}
@@ -0,0 +1,245 @@
+package lsp_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/geraldywy/monkey/lsp"
+)
+
+const uri = "file:///src/main.mk"
+
+type message struct {
+	ID     *int            `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// session runs the server over the given requests, each is a method and its params, and
+// returns the messages written back. Requests are numbered from 1 unless they are
+// notifications, given by a method prefixed with "!".
+func session(t *testing.T, requests ...[2]string) []message {
+	t.Helper()
+	var in bytes.Buffer
+	for i, r := range requests {
+		content := fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"method":%q,"params":%s}`, i+1, r[0], r[1])
+		if strings.HasPrefix(r[0], "!") {
+			content = fmt.Sprintf(`{"jsonrpc":"2.0","method":%q,"params":%s}`, r[0][1:], r[1])
+		}
+		fmt.Fprintf(&in, "Content-Length: %d\r\n\r\n%s", len(content), content)
+	}
+	var out bytes.Buffer
+	if err := lsp.NewServer(&in, &out).Serve(); err != nil {
+		t.Fatalf("Serve returned error: %v", err)
+	}
+
+	msgs := []message{}
+	r := bufio.NewReader(&out)
+	for {
+		header, err := textproto.NewReader(r).ReadMIMEHeader()
+		if err == io.EOF {
+			return msgs
+		}
+		if err != nil {
+			t.Fatalf("bad header: %v", err)
+		}
+		length, _ := strconv.Atoi(header.Get("Content-Length"))
+		content := make([]byte, length)
+		if _, err := io.ReadFull(r, content); err != nil {
+			t.Fatalf("bad content: %v", err)
+		}
+		var msg message
+		if err := json.Unmarshal(content, &msg); err != nil {
+			t.Fatalf("bad message %s: %v", content, err)
+		}
+		msgs = append(msgs, msg)
+	}
+}
+
+func open(text string) [2]string {
+	raw, _ := json.Marshal(text)
+	return [2]string{"!textDocument/didOpen", fmt.Sprintf(`{"textDocument":{"uri":%q,"languageId":"monkey","version":1,"text":%s}}`, uri, raw)}
+}
+
+func at(method string, line, char int) [2]string {
+	return [2]string{method, fmt.Sprintf(`{"textDocument":{"uri":%q},"position":{"line":%d,"character":%d},"context":{"includeDeclaration":true}}`, uri, line, char)}
+}
+
+// result returns the result of the request with the given id
+func result(t *testing.T, msgs []message, id int) string {
+	t.Helper()
+	for _, msg := range msgs {
+		if msg.ID != nil && *msg.ID == id {
+			if msg.Error != nil {
+				t.Fatalf("request %d failed: %s", id, msg.Error.Message)
+			}
+			return string(msg.Result)
+		}
+	}
+	t.Fatalf("no response to request %d", id)
+	return ""
+}
+
+func TestInitializeAndShutdown(t *testing.T) {
+	msgs := session(t,
+		[2]string{"initialize", `{"capabilities":{}}`},
+		[2]string{"!initialized", `{}`},
+		[2]string{"unknown/method", `{}`},
+		[2]string{"shutdown", `null`},
+		[2]string{"!exit", `null`},
+	)
+	if len(msgs) != 3 {
+		t.Fatalf("wrong number of messages. want=3, got=%d", len(msgs))
+	}
+	if got := result(t, msgs, 1); !strings.Contains(got, `"textDocumentSync":1`) || !strings.Contains(got, `"hoverProvider":true`) {
+		t.Errorf("capabilities wrong. got=%s", got)
+	}
+	if msgs[1].Error == nil || msgs[1].Error.Code != -32601 {
+		t.Errorf("expected method not found error. got=%+v", msgs[1].Error)
+	}
+	if got := result(t, msgs, 4); got != "null" {
+		t.Errorf("shutdown result wrong. got=%s", got)
+	}
+}
+
+func TestExitWithoutShutdown(t *testing.T) {
+	in := strings.NewReader("Content-Length: 33\r\n\r\n{\"jsonrpc\":\"2.0\",\"method\":\"exit\"}")
+	if err := lsp.NewServer(in, io.Discard).Serve(); err != lsp.ErrNoShutdown {
+		t.Errorf("wrong error. want=%v, got=%v", lsp.ErrNoShutdown, err)
+	}
+}
+
+func TestDiagnostics(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string // line:character message, zero based
+	}{
+		{"let x = 5;", []string{}},
+		{"let = 5;\nlet y = 1 +;", []string{
+			"0:3 expected one of tokens: [IDENT [ {], got =",
+			"0:4 no prefix parse function for = found",
+			"1:11 no prefix parse function for ; found",
+		}},
+		{"let s = \"abc", []string{"0:11 unterminated string literal"}},
+		{"let 1a = 2;", []string{"0:4 bad variable name"}},
+		{"let s = \"😀\"; let = 5;", []string{"0:17 expected one of tokens: [IDENT [ {], got =", "0:18 no prefix parse function for = found"}},
+	}
+	for _, tt := range tests {
+		msgs := session(t, open(tt.input))
+		if len(msgs) != 1 || msgs[0].Method != "textDocument/publishDiagnostics" {
+			t.Fatalf("%q - expected diagnostics to be published. got=%+v", tt.input, msgs)
+		}
+		var params lsp.PublishDiagnosticsParams
+		json.Unmarshal(msgs[0].Params, &params)
+		got := []string{}
+		for _, d := range params.Diagnostics {
+			got = append(got, fmt.Sprintf("%d:%d %s", d.Range.Start.Line, d.Range.Start.Character, d.Message))
+		}
+		if fmt.Sprint(got) != fmt.Sprint(tt.expected) {
+			t.Errorf("%q - diagnostics wrong.\nwant=%q\ngot=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+const program = `let add = fn(a, b) { a + b };
+let x: int = add(1, 2);
+let y = fn(n) { let z = n; z + x };
+add(x, y(x));`
+
+func TestNavigation(t *testing.T) {
+	msgs := session(t,
+		open(program),
+		at("textDocument/hover", 3, 1),
+		at("textDocument/hover", 1, 5),
+		at("textDocument/hover", 2, 11),
+		at("textDocument/hover", 0, 19),
+		at("textDocument/definition", 3, 8),
+		at("textDocument/references", 1, 4),
+		at("textDocument/definition", 3, 12),
+	)
+	tests := []struct {
+		id       int
+		expected string
+	}{
+		{2, `{"contents":{"kind":"markdown","value":"` + "```monkey\\nlet add = fn(a, b)\\n```\\n\\n`add: fn('a, 'a) -\\u003e 'a where 'a: int | string`" + `"},"range":{"start":{"line":3,"character":0},"end":{"line":3,"character":3}}}`},
+		{3, `{"contents":{"kind":"markdown","value":"` + "```monkey\\nlet x: int = add(1, 2);\\n```\\n\\n`x: int`" + `"},"range":{"start":{"line":1,"character":4},"end":{"line":1,"character":5}}}`},
+		{4, `{"contents":{"kind":"markdown","value":"` + "```monkey\\n(parameter) n\\n```\\n\\n`n: int`" + `"},"range":{"start":{"line":2,"character":11},"end":{"line":2,"character":12}}}`},
+		{5, `null`},
+		{6, `{"uri":"file:///src/main.mk","range":{"start":{"line":2,"character":4},"end":{"line":2,"character":5}}}`},
+		{7, `[{"uri":"file:///src/main.mk","range":{"start":{"line":1,"character":4},"end":{"line":1,"character":5}}},` +
+			`{"uri":"file:///src/main.mk","range":{"start":{"line":2,"character":31},"end":{"line":2,"character":32}}},` +
+			`{"uri":"file:///src/main.mk","range":{"start":{"line":3,"character":4},"end":{"line":3,"character":5}}},` +
+			`{"uri":"file:///src/main.mk","range":{"start":{"line":3,"character":9},"end":{"line":3,"character":10}}}]`},
+		{8, `null`},
+	}
+	for _, tt := range tests {
+		if got := result(t, msgs, tt.id); got != tt.expected {
+			t.Errorf("request %d - result wrong.\nwant=%s\ngot=%s", tt.id, tt.expected, got)
+		}
+	}
+}
+
+func TestUTF16Positions(t *testing.T) {
+	// é is one UTF-16 code unit but two bytes, 😀 is two code units but four bytes
+	msgs := session(t, open("let s = \"é😀\"; let t = s;"), at("textDocument/references", 0, 23))
+	expected := `[{"uri":"file:///src/main.mk","range":{"start":{"line":0,"character":4},"end":{"line":0,"character":5}}},` +
+		`{"uri":"file:///src/main.mk","range":{"start":{"line":0,"character":23},"end":{"line":0,"character":24}}}]`
+	if got := result(t, msgs, 2); got != expected {
+		t.Errorf("references wrong.\nwant=%s\ngot=%s", expected, got)
+	}
+}
+
+func TestDocumentSymbols(t *testing.T) {
+	msgs := session(t, open(program), [2]string{"textDocument/documentSymbol", fmt.Sprintf(`{"textDocument":{"uri":%q}}`, uri)})
+	var syms []lsp.DocumentSymbol
+	json.Unmarshal([]byte(result(t, msgs, 2)), &syms)
+	expected := []string{"add 12 fn(a, b) 0:0", "x 13  1:0", "y 12 fn(n) 2:0"}
+	got := []string{}
+	for _, s := range syms {
+		got = append(got, fmt.Sprintf("%s %d %s %d:%d", s.Name, s.Kind, s.Detail, s.Range.Start.Line, s.Range.Start.Character))
+	}
+	if fmt.Sprint(got) != fmt.Sprint(expected) {
+		t.Errorf("symbols wrong.\nwant=%q\ngot=%q", expected, got)
+	}
+}
+
+func TestCompletion(t *testing.T) {
+	tests := []struct {
+		line, char int
+		expected   []string // the identifiers offered, after the keywords
+	}{
+		{0, 0, []string{"y", "x", "add", "unquote", "quote"}},
+		{2, 28, []string{"z", "n", "y", "x", "add", "unquote", "quote"}},
+		{0, 24, []string{"b", "a", "y", "x", "add", "unquote", "quote"}},
+		{0, 27, []string{"b", "a", "y", "x", "add", "unquote", "quote"}},
+		{0, 28, []string{"y", "x", "add", "unquote", "quote"}},
+	}
+	for _, tt := range tests {
+		msgs := session(t, open(program), at("textDocument/completion", tt.line, tt.char))
+		var items []lsp.CompletionItem
+		json.Unmarshal([]byte(result(t, msgs, 2)), &items)
+		got := []string{}
+		for _, item := range items {
+			if item.Kind != lsp.CompletionKeyword {
+				got = append(got, item.Label)
+			} else if item.Label == "" {
+				t.Errorf("empty keyword")
+			}
+		}
+		if fmt.Sprint(got) != fmt.Sprint(tt.expected) {
+			t.Errorf("%d:%d - completions wrong.\nwant=%q\ngot=%q", tt.line, tt.char, tt.expected, got)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package macro
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/geraldywy/monkey/ast"
+)
+
+// maxDepth bounds how many times the result of an expansion may itself be expanded,
+// guarding against macros that expand into calls to themselves
+const maxDepth = 100
+
+// Quote is the value produced by quote(expr), it holds the unevaluated AST of expr
+type Quote struct {
+	Node ast.Node
+}
+
+func (q *Quote) Inspect() string {
+	return "QUOTE(" + q.Node.String() + ")"
+}
+
+// Env holds the macros defined by a program, keyed by the name they are bound to
+type Env map[string]*ast.MacroLiteral
+
+// DefineMacros removes the top level macro definitions, e.g. let unless = macro(c, a, b) { ... };
+// from program, returning them for ExpandMacros.
+func DefineMacros(program *ast.Program) Env {
+	env := make(Env)
+	stmts := make([]ast.Statement, 0, len(program.Statements))
+	for _, stmt := range program.Statements {
+		if let, ok := stmt.(*ast.LetStatement); ok && let.Name != nil {
+			if macro, ok := let.Value.(*ast.MacroLiteral); ok {
+				env[let.Name.Value] = macro
+				continue
+			}
+		}
+		stmts = append(stmts, stmt)
+	}
+	program.Statements = stmts
+
+	return env
+}
+
+// ExpandMacros rewrites every call to a macro in env with the AST the macro expands to.
+//
+// Macro bodies are not evaluated, the body must be a single quote(...) whose unquote(...)
+// calls each name a macro parameter. The parameter is then spliced in as the quoted
+// argument it is bound to.
+func ExpandMacros(program ast.Node, env Env) (ast.Node, error) {
+	return expandMacros(program, env, 0)
+}
+
+func expandMacros(node ast.Node, env Env, depth int) (ast.Node, error) {
+	if depth > maxDepth {
+		return nil, errors.New(fmt.Sprintf("macro expansion exceeded maximum depth of %d", maxDepth))
+	}
+
+	var expandErr error
+	expanded := ast.Modify(node, func(node ast.Node) ast.Node {
+		call, ok := node.(*ast.CallExpression)
+		if !ok || expandErr != nil {
+			return node
+		}
+		ident, ok := call.Function.(*ast.Identifier)
+		if !ok {
+			return node
+		}
+		macro, ok := env[ident.Value]
+		if !ok {
+			return node
+		}
+
+		quote, err := expand(ident.Value, macro, call.Arguments)
+		if err != nil {
+			expandErr = err
+			return node
+		}
+		// the expansion may itself contain macro calls
+		result, err := expandMacros(quote.Node, env, depth+1)
+		if err != nil {
+			expandErr = err
+			return node
+		}
+		return result
+	})
+	if expandErr != nil {
+		return nil, expandErr
+	}
+
+	return expanded, nil
+}
+
+func expand(name string, macro *ast.MacroLiteral, args []ast.Expression) (*Quote, error) {
+	if len(args) != len(macro.Parameters) {
+		return nil, errors.New(fmt.Sprintf(
+			"wrong number of arguments to macro %s: want=%d, got=%d",
+			name,
+			len(macro.Parameters),
+			len(args),
+		))
+	}
+	bindings := make(map[string]*Quote)
+	for i, param := range macro.Parameters {
+		switch args[i].(type) {
+		case *ast.SpreadExpression, *ast.NamedArgument:
+			return nil, errors.New(fmt.Sprintf(
+				"argument %s to macro %s must be a plain expression",
+				args[i].String(),
+				name,
+			))
+		}
+		bindings[param.Value] = &Quote{Node: args[i]}
+	}
+
+	template, ok := quotedTemplate(macro)
+	if !ok {
+		return nil, errors.New(fmt.Sprintf(
+			"macro %s must consist of a single quote(...) expression, got %s",
+			name,
+			macro.Body.String(),
+		))
+	}
+
+	return quote(name, template, bindings)
+}
+
+// quotedTemplate returns the argument of the quote(...) call making up a macro's body
+func quotedTemplate(macro *ast.MacroLiteral) (ast.Expression, bool) {
+	if len(macro.Body.Statements) != 1 {
+		return nil, false
+	}
+
+	var exp ast.Expression
+	switch stmt := macro.Body.Statements[0].(type) {
+	case *ast.ExpressionStatement:
+		exp = stmt.Expression
+	case *ast.ReturnStatement:
+		exp = stmt.ReturnValue
+	}
+	if arg, ok := builtinCallArg(exp, "quote"); ok {
+		return arg, true
+	}
+
+	return nil, false
+}
+
+// quote builds the AST of a quote(...) call, splicing in the quoted argument bound to
+// each unquote(param)
+func quote(name string, template ast.Expression, bindings map[string]*Quote) (*Quote, error) {
+	var unquoteErr error
+	node := ast.Modify(template, func(node ast.Node) ast.Node {
+		arg, ok := builtinCallArg(node, "unquote")
+		if !ok || unquoteErr != nil {
+			return node
+		}
+		if ident, ok := arg.(*ast.Identifier); ok {
+			if q, ok := bindings[ident.Value]; ok {
+				return q.Node
+			}
+		}
+		unquoteErr = errors.New(fmt.Sprintf(
+			"unquote(%s) in macro %s must name one of the macro's parameters",
+			arg.String(),
+			name,
+		))
+		return node
+	})
+	if unquoteErr != nil {
+		return nil, unquoteErr
+	}
+
+	return &Quote{Node: node}, nil
+}
+
+// builtinCallArg returns the sole argument of a call to the builtin fnName, e.g. quote(x)
+func builtinCallArg(node ast.Node, fnName string) (ast.Expression, bool) {
+	call, ok := node.(*ast.CallExpression)
+	if !ok || len(call.Arguments) != 1 {
+		return nil, false
+	}
+	ident, ok := call.Function.(*ast.Identifier)
+	if !ok || ident.Value != fnName {
+		return nil, false
+	}
+
+	return call.Arguments[0], true
+}
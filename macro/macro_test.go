@@ -0,0 +1,140 @@
+package macro
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/geraldywy/monkey/ast"
+	"github.com/geraldywy/monkey/lexer"
+	"github.com/geraldywy/monkey/parser"
+)
+
+func parse(t *testing.T, input string) *ast.Program {
+	p := parser.New(lexer.New(input, "macro_test.go"))
+	program := p.ParseProgram()
+	if len(p.Errors) != 0 {
+		t.Fatalf("parser errors for %q: %v", input, p.Errors)
+	}
+	return program
+}
+
+func TestDefineMacros(t *testing.T) {
+	input := `
+	let number = 1;
+	let function = fn(x, y) { x + y };
+	let mymacro = macro(x, y) { quote(x + y); };
+	`
+	program := parse(t, input)
+	env := DefineMacros(program)
+
+	if len(program.Statements) != 2 {
+		t.Fatalf("wrong number of statements. got=%d", len(program.Statements))
+	}
+	if _, ok := env["number"]; ok {
+		t.Fatalf("number should not be defined")
+	}
+	if _, ok := env["function"]; ok {
+		t.Fatalf("function should not be defined")
+	}
+	macro, ok := env["mymacro"]
+	if !ok {
+		t.Fatalf("macro not in environment.")
+	}
+	if len(macro.Parameters) != 2 {
+		t.Fatalf("wrong number of macro parameters. got=%d", len(macro.Parameters))
+	}
+	if macro.Body.String() != "{ quote((x + y)) }" {
+		t.Fatalf("body is not %q. got=%q", "{ quote((x + y)) }", macro.Body.String())
+	}
+}
+
+func TestExpandMacros(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			`let infixExpression = macro() { quote(1 + 2); };
+			infixExpression();`,
+			`(1 + 2)`,
+		},
+		{
+			`let reverse = macro(a, b) { quote(unquote(b) - unquote(a)); };
+			reverse(2 + 2, 10 - 5);`,
+			`(10 - 5) - (2 + 2)`,
+		},
+		{
+			`let unless = macro(cond, cons, alt) {
+				quote(if (!(unquote(cond))) { unquote(cons); } else { unquote(alt); });
+			};
+			unless(10 > 5, puts("not greater"), puts("greater"));`,
+			`if (!(10 > 5)) { puts("not greater") } else { puts("greater") }`,
+		},
+		{
+			`let double = macro(x) { quote(unquote(x) * 2); };
+			let quadruple = macro(x) { quote(double(double(unquote(x)))); };
+			quadruple(y);`,
+			`(y * 2) * 2`,
+		},
+		{
+			`let id = macro(x) { quote(unquote(x)); };
+			fn(a) { id(a) + 1 };`,
+			`fn(a) { a + 1 }`,
+		},
+	}
+	for _, tt := range tests {
+		program := parse(t, tt.input)
+		env := DefineMacros(program)
+		expanded, err := ExpandMacros(program, env)
+		if err != nil {
+			t.Fatalf("unexpected error for %q: %s", tt.input, err)
+		}
+		expected := parse(t, tt.expected)
+		if expanded.String() != expected.String() {
+			t.Errorf("not equal. want=%q, got=%q", expected.String(), expanded.String())
+		}
+	}
+}
+
+func TestExpandMacrosLeavesOriginalUntouched(t *testing.T) {
+	program := parse(t, `let twice = macro(x) { quote(unquote(x) + unquote(x)); }; twice(1); twice(2);`)
+	env := DefineMacros(program)
+	if _, err := ExpandMacros(program, env); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if program.String() != "twice(1);twice(2)" {
+		t.Errorf("program was modified. got=%q", program.String())
+	}
+	if env["twice"].Body.String() != "{ quote((unquote(x) + unquote(x))) }" {
+		t.Errorf("macro body was modified. got=%q", env["twice"].Body.String())
+	}
+}
+
+func TestExpandMacrosErrors(t *testing.T) {
+	tests := []struct {
+		input       string
+		errContains string
+	}{
+		{`let m = macro(a) { quote(a); }; m();`, "wrong number of arguments to macro m"},
+		{`let m = macro(a) { let b = a; quote(b); }; m(1);`, "must consist of a single quote(...)"},
+		{`let m = macro(a) { quote(unquote(a + 1)); }; m(1);`, "unquote((a + 1)) in macro m"},
+		{`let m = macro(a) { quote(unquote(a)); }; m(...xs);`, "must be a plain expression"},
+		{`let m = macro(a) { quote(m(unquote(a))); }; m(1);`, "exceeded maximum depth"},
+	}
+	for _, tt := range tests {
+		program := parse(t, tt.input)
+		env := DefineMacros(program)
+		_, err := ExpandMacros(program, env)
+		if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+			t.Errorf("expected error containing %q for %q, got=%v", tt.errContains, tt.input, err)
+		}
+	}
+}
+
+func TestQuoteInspect(t *testing.T) {
+	program := parse(t, `5 + 8`)
+	q := &Quote{Node: program.Statements[0].(*ast.ExpressionStatement).Expression}
+	if q.Inspect() != "QUOTE((5 + 8))" {
+		t.Errorf("q.Inspect() wrong. got=%q", q.Inspect())
+	}
+}
@@ -4,11 +4,36 @@ import (
 	"fmt"
 	"os"
 	"os/user"
+	"sort"
+	"strings"
 
 	"github.com/geraldywy/monkey/repl"
 )
 
+// commands maps each subcommand to its entry point, which returns the exit code
+var commands = map[string]func(args []string) int{
+	"parse": parseCmd,
+	"fmt":   fmtCmd,
+	"vet":   vetCmd,
+	"check": checkCmd,
+	"lsp":   lspCmd,
+}
+
 func main() {
+	if len(os.Args) > 1 {
+		cmd, ok := commands[os.Args[1]]
+		if !ok {
+			names := make([]string, 0, len(commands))
+			for name := range commands {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			fmt.Fprintf(os.Stderr, "unknown command %q, expected one of: %s\n", os.Args[1], strings.Join(names, ", "))
+			os.Exit(2)
+		}
+		os.Exit(cmd(os.Args[2:]))
+	}
+
 	user, err := user.Current()
 	if err != nil {
 		panic(err)
@@ -0,0 +1,9 @@
+package module
+
+import "errors"
+
+var (
+	ErrNotFound    = errors.New("module not found")
+	ErrImportCycle = errors.New("import cycle not allowed")
+	ErrNotExported = errors.New("name not exported")
+)
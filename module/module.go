@@ -0,0 +1,188 @@
+package module
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/geraldywy/monkey/ast"
+	"github.com/geraldywy/monkey/lexer"
+	"github.com/geraldywy/monkey/parser"
+	"github.com/geraldywy/monkey/resolver"
+)
+
+// Module is a parsed source file, along with the modules it imports
+type Module struct {
+	Path    string // cleaned absolute path the module was loaded from
+	Program *ast.Program
+	Exports map[string]*ast.Identifier
+	Imports map[string]*Module // keyed by the alias the module is imported as
+}
+
+// Export returns the declaration of an exported binding, e.g. sqrt for m.sqrt
+func (m *Module) Export(name string) (*ast.Identifier, bool) {
+	ident, ok := m.Exports[name]
+	return ident, ok
+}
+
+// Loader resolves, parses and caches modules. An interpreter should use a single
+// Loader so that every module is loaded once, no matter how many times it is imported.
+type Loader struct {
+	// SearchPath lists the directories tried, in order, when an import is not
+	// found relative to the importing file
+	SearchPath []string
+
+	modules map[string]*Module
+	loading []string // the chain of modules currently being loaded, for cycle detection
+}
+
+func NewLoader(searchPath ...string) *Loader {
+	return &Loader{
+		SearchPath: searchPath,
+		modules:    make(map[string]*Module),
+	}
+}
+
+// Load loads the module at path, along with everything it imports
+func (l *Loader) Load(path string) (*Module, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return l.load(absPath)
+}
+
+func (l *Loader) load(absPath string) (*Module, error) {
+	if m, ok := l.modules[absPath]; ok {
+		return m, nil
+	}
+	for i, loading := range l.loading {
+		if loading == absPath {
+			cycle := append(append([]string{}, l.loading[i:]...), absPath)
+			return nil, fmt.Errorf("%w: %s", ErrImportCycle, strings.Join(cycle, " -> "))
+		}
+	}
+	l.loading = append(l.loading, absPath)
+	defer func() { l.loading = l.loading[:len(l.loading)-1] }()
+
+	src, err := os.ReadFile(absPath)
+	if err != nil {
+		return nil, err
+	}
+	p := parser.New(lexer.New(string(src), absPath))
+	prog := p.ParseProgram()
+	if len(p.Errors) != 0 {
+		msgs := make([]string, 0, len(p.Errors))
+		for _, err := range p.Errors {
+			msgs = append(msgs, err.Error())
+		}
+		return nil, errors.New(strings.Join(msgs, "\n"))
+	}
+
+	m := &Module{
+		Path:    absPath,
+		Program: prog,
+		Exports: make(map[string]*ast.Identifier),
+		Imports: make(map[string]*Module),
+	}
+	for _, stmt := range prog.Statements {
+		switch stmt := stmt.(type) {
+		case *ast.ImportStatement:
+			if _, ok := m.Imports[stmt.Alias.Value]; ok {
+				return nil, errors.New(fmt.Sprintf(
+					"%s: %s imported more than once",
+					absPath,
+					stmt.Alias.Value,
+				))
+			}
+			resolved, err := l.resolve(absPath, stmt.Path.Value)
+			if err != nil {
+				return nil, err
+			}
+			imported, err := l.load(resolved)
+			if err != nil {
+				return nil, err
+			}
+			m.Imports[stmt.Alias.Value] = imported
+		case *ast.ExportStatement:
+			names := []*ast.Identifier{stmt.Statement.Name}
+			if stmt.Statement.Pattern != nil {
+				names = ast.Bindings(stmt.Statement.Pattern)
+			}
+			for _, name := range names {
+				if _, ok := m.Exports[name.Value]; ok {
+					return nil, errors.New(fmt.Sprintf(
+						"%s: %s exported more than once",
+						absPath,
+						name.Value,
+					))
+				}
+				m.Exports[name.Value] = name
+			}
+		}
+	}
+	if err := m.checkMembers(); err != nil {
+		return nil, err
+	}
+	l.modules[absPath] = m
+
+	return m, nil
+}
+
+// checkMembers returns an error for the first access through an import alias, e.g. m.sqrt,
+// to a name the imported module does not export
+func (m *Module) checkMembers() error {
+	info, _ := resolver.Resolve(m.Program, m.Path)
+	var err error
+	ast.Inspect(m.Program, func(n ast.Node) bool {
+		me, ok := n.(*ast.MemberExpression)
+		if !ok || err != nil {
+			return err == nil
+		}
+		alias, ok := me.Object.(*ast.Identifier)
+		if !ok {
+			return true
+		}
+		// the alias may be shadowed by a local of the same name
+		if sym := info.Uses[alias]; sym == nil || sym.Kind != resolver.Import {
+			return true
+		}
+		imported, ok := m.Imports[alias.Value]
+		if !ok {
+			return true
+		}
+		if _, ok := imported.Export(me.Property.Value); !ok {
+			err = fmt.Errorf("%s line: %d col: %d %s.%s: %w by %s",
+				m.Path, me.Property.Token.Line, me.Property.Token.Column,
+				alias.Value, me.Property.Value, ErrNotExported, imported.Path)
+		}
+		return true
+	})
+
+	return err
+}
+
+// resolve finds the file an import refers to, first relative to the importing
+// file's directory, then in each directory of the search path
+func (l *Loader) resolve(importer string, path string) (string, error) {
+	if filepath.IsAbs(path) {
+		return filepath.Clean(path), nil
+	}
+
+	dirs := append([]string{filepath.Dir(importer)}, l.SearchPath...)
+	for _, dir := range dirs {
+		cand, err := filepath.Abs(filepath.Join(dir, path))
+		if err != nil {
+			return "", err
+		}
+		if info, err := os.Stat(cand); err == nil && !info.IsDir() {
+			return cand, nil
+		}
+	}
+
+	return "", fmt.Errorf("%w: %q imported by %s, searched %s",
+		ErrNotFound, path, importer, strings.Join(dirs, ", "))
+}
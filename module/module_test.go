@@ -0,0 +1,144 @@
+package module
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, src := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestLoadResolvesAndCachesImports(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"main.mk":     `import "lib/math.mk" as m; import "util.mk" as u; m.sqrt(4);`,
+		"lib/math.mk": `import "../util.mk" as u; export let sqrt = fn(x) { x }; let hidden = 1;`,
+		"util.mk":     `export let [first, ...others] = xs;`,
+	})
+
+	l := NewLoader()
+	main, err := l.Load(filepath.Join(dir, "main.mk"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	math, ok := main.Imports["m"]
+	if !ok {
+		t.Fatalf("main.Imports missing m. got=%v", main.Imports)
+	}
+	if _, ok := math.Export("sqrt"); !ok {
+		t.Errorf("math does not export sqrt")
+	}
+	if _, ok := math.Export("hidden"); ok {
+		t.Errorf("math exports unexported binding hidden")
+	}
+	if main.Imports["u"] != math.Imports["u"] {
+		t.Errorf("util.mk was loaded more than once")
+	}
+	for _, name := range []string{"first", "others"} {
+		if _, ok := main.Imports["u"].Export(name); !ok {
+			t.Errorf("util does not export %s", name)
+		}
+	}
+}
+
+func TestLoadSearchPath(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"app/main.mk":       `import "strings.mk" as s;`,
+		"stdlib/strings.mk": `export let upper = fn(x) { x };`,
+	})
+
+	if _, err := NewLoader().Load(filepath.Join(dir, "app/main.mk")); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound without a search path, got=%v", err)
+	}
+
+	main, err := NewLoader(filepath.Join(dir, "stdlib")).Load(filepath.Join(dir, "app/main.mk"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, ok := main.Imports["s"].Export("upper"); !ok {
+		t.Errorf("strings does not export upper")
+	}
+}
+
+func TestLoadImportCycle(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"a.mk": `import "b.mk" as b;`,
+		"b.mk": `import "c.mk" as c;`,
+		"c.mk": `import "a.mk" as a;`,
+	})
+
+	_, err := NewLoader().Load(filepath.Join(dir, "a.mk"))
+	if !errors.Is(err, ErrImportCycle) {
+		t.Fatalf("expected ErrImportCycle, got=%v", err)
+	}
+	want := strings.Join([]string{
+		filepath.Join(dir, "a.mk"),
+		filepath.Join(dir, "b.mk"),
+		filepath.Join(dir, "c.mk"),
+		filepath.Join(dir, "a.mk"),
+	}, " -> ")
+	if !strings.Contains(err.Error(), want) {
+		t.Errorf("cycle not reported. want=%q, got=%q", want, err.Error())
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := map[string]string{
+		"duplicate export": `export let a = 1; export let a = 2;`,
+		"duplicate alias":  `import "lib.mk" as m; import "lib.mk" as m;`,
+		"parse error":      `let = 1;`,
+	}
+	for name, src := range tests {
+		dir := writeFiles(t, map[string]string{"main.mk": src, "lib.mk": ``})
+		if _, err := NewLoader().Load(filepath.Join(dir, "main.mk")); err == nil {
+			t.Errorf("%s: expected an error, got none", name)
+		}
+	}
+}
+
+func TestLoadChecksMembers(t *testing.T) {
+	tests := []struct {
+		src      string
+		expected string // the position and name reported, empty if there is no error
+	}{
+		{`import "lib.mk" as m; m.sqrt(4);`, ""},
+		{`import "lib.mk" as m; m.hidden;`, "line: 1 col: 25 m.hidden"},
+		{`import "lib.mk" as m;
+let f = fn() { m.nope(1) };`, "line: 2 col: 18 m.nope"},
+		{`import "lib.mk" as m; let g = fn(m) { m.anything };`, ""},
+		{`import "lib.mk" as m; let x = m.sqrt; x.anything;`, ""},
+	}
+	for _, tt := range tests {
+		dir := writeFiles(t, map[string]string{
+			"main.mk": tt.src,
+			"lib.mk":  `export let sqrt = fn(x) { x }; let hidden = 1;`,
+		})
+		_, err := NewLoader().Load(filepath.Join(dir, "main.mk"))
+		if tt.expected == "" {
+			if err != nil {
+				t.Errorf("%q - unexpected error: %s", tt.src, err)
+			}
+			continue
+		}
+		if !errors.Is(err, ErrNotExported) {
+			t.Errorf("%q - expected ErrNotExported, got=%v", tt.src, err)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("%q - error wrong. want it to contain %q, got=%q", tt.src, tt.expected, err.Error())
+		}
+	}
+}
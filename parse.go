@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/geraldywy/monkey/ast"
+	"github.com/geraldywy/monkey/lexer"
+	"github.com/geraldywy/monkey/parser"
+)
+
+func parseCmd(args []string) int {
+	fs := flag.NewFlagSet("parse", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "print the AST as JSON instead of source")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: monkey parse [--json] <file>")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+
+	program, ok := parseFile(fs.Arg(0))
+	if !ok {
+		return 1
+	}
+	if !*asJSON {
+		fmt.Println(program.String())
+		return 0
+	}
+
+	encoded, err := ast.EncodeJSON(program)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	var out bytes.Buffer
+	if err := json.Indent(&out, encoded, "", "  "); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	out.WriteString("\n")
+	os.Stdout.Write(out.Bytes())
+
+	return 0
+}
+
+// parseFile parses the file at path, reporting any read or parser errors to stderr
+func parseFile(path string) (*ast.Program, bool) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return nil, false
+	}
+	p := parser.New(lexer.New(string(src), path))
+	program := p.ParseProgram()
+	if len(p.Errors) != 0 {
+		for _, err := range p.Errors {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		return nil, false
+	}
+
+	return program, true
+}
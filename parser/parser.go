@@ -3,6 +3,7 @@ package parser
 import (
 	"errors"
 	"fmt"
+	"math/big"
 	"strconv"
 
 	"github.com/geraldywy/monkey/utils"
@@ -15,12 +16,19 @@ import (
 const (
 	_ int = iota
 	LOWEST
+	NULLISH     // ??
+	BITOR       // |
+	BITXOR      // ^
+	BITAND      // &
 	EQUALS      // ==
 	LESSGREATER // > or <
+	SHIFT       // << or >>
 	SUM         //+
-	PRODUCT     //*
-	PREFIX      // -X or !X
+	PRODUCT     //* or / or %
+	PREFIX      // -X or !X or ~X
+	POWER       // **, binds tighter than a prefix operator on its left, -2 ** 2 is -(2 ** 2)
 	CALL        // myFunction(X)
+	INDEX       // array[index], obj.member or their ?[ ?. forms
 )
 
 type (
@@ -32,8 +40,27 @@ type Parser struct {
 	l      *lexer.Lexer
 	Errors []error
 
-	prefixParseFns map[token.TokenType]prefixParseFn
-	infixParseFns  map[token.TokenType]infixParseFn
+	prefixParseFns  map[token.TokenType]prefixParseFn
+	infixParseFns   map[token.TokenType]infixParseFn
+	precedences     map[token.TokenType]int
+	associativities map[token.TokenType]Associativity
+
+	// set while parsing a match guard, where 'x => ...' ends the guard rather than starting an arrow function
+	noBareArrow bool
+	// number of function bodies enclosing the current token, defer is only allowed inside one
+	fnDepth int
+	// number of blocks enclosing the current token, import and export are only allowed at the top level
+	blockDepth int
+	// the positions a '(' was found not to start an arrow function parameter list at, so that
+	// nested groupings are not tried as parameter lists over and over again
+	notArrowParams map[arrowAttempt]bool
+}
+
+// arrowAttempt is where an arrow function parameter list was tried, along with the setting
+// its default values were parsed under
+type arrowAttempt struct {
+	state       lexer.State
+	noBareArrow bool
 }
 
 func New(l *lexer.Lexer) *Parser {
@@ -47,32 +74,133 @@ func New(l *lexer.Lexer) *Parser {
 		token.MINUS:      p.parsePrefixExpression,
 		token.MINUSMINUS: p.parsePrefixExpression,
 		token.PLUSPLUS:   p.parsePrefixExpression,
+		token.TILDE:      p.parsePrefixExpression,
 		token.LPAREN:     p.parseGroupedExpression,
 		token.IF:         p.parseIfExpression,
 		token.FUNCTION:   p.parseFunctionLiteral,
+		token.MATCH:      p.parseMatchExpression,
+		token.NULL:       p.parseNullLiteral,
+		token.TRY:        p.parseTryExpression,
+		token.STRING:     p.parseStringLiteral,
+		token.MACRO:      p.parseMacroLiteral,
 	}
 	p.infixParseFns = map[token.TokenType]infixParseFn{
-		token.PLUS:     p.parseInfixExpression,
-		token.MINUS:    p.parseInfixExpression,
-		token.SLASH:    p.parseInfixExpression,
-		token.ASTERISK: p.parseInfixExpression,
-		token.EQ:       p.parseInfixExpression,
-		token.NEQ:      p.parseInfixExpression,
-		token.LT:       p.parseInfixExpression,
-		token.GT:       p.parseInfixExpression,
-		token.LPAREN:   p.parseCallExpression,
+		token.PLUS:      p.parseInfixExpression,
+		token.MINUS:     p.parseInfixExpression,
+		token.SLASH:     p.parseInfixExpression,
+		token.ASTERISK:  p.parseInfixExpression,
+		token.EQ:        p.parseInfixExpression,
+		token.NEQ:       p.parseInfixExpression,
+		token.LT:        p.parseInfixExpression,
+		token.GT:        p.parseInfixExpression,
+		token.PERCENT:   p.parseInfixExpression,
+		token.POWER:     p.parseInfixExpression,
+		token.AMPERSAND: p.parseInfixExpression,
+		token.PIPE:      p.parseInfixExpression,
+		token.CARET:     p.parseInfixExpression,
+		token.LSHIFT:    p.parseInfixExpression,
+		token.RSHIFT:    p.parseInfixExpression,
+		token.LPAREN:    p.parseCallExpression,
+		token.NULLISH:   p.parseInfixExpression,
+		token.DOT:       p.parseMemberExpression,
+		token.OPTDOT:    p.parseMemberExpression,
+		token.LBRACKET:  p.parseIndexExpression,
+		token.OPTLBRACK: p.parseIndexExpression,
+	}
+	// copied so operators registered on one parser do not leak into another
+	p.precedences = make(map[token.TokenType]int, len(precedences))
+	for tt, pred := range precedences {
+		p.precedences[tt] = pred
+	}
+	p.associativities = make(map[token.TokenType]Associativity, len(associativities))
+	for tt, assoc := range associativities {
+		p.associativities[tt] = assoc
 	}
 
 	return p
 }
 
+// RegisterPrefix adds a unary operator, e.g. RegisterPrefix("@") parses @x into an
+// ast.PrefixExpression binding as tightly as -x. The operator is a word or made up of
+// symbols only, and is registered with the parser's lexer if it does not already emit it.
+func (p *Parser) RegisterPrefix(operator string) error {
+	tt, err := p.registerOperator(operator)
+	if err != nil {
+		return err
+	}
+	if _, exist := p.prefixParseFns[tt]; exist {
+		return errors.New(fmt.Sprintf("prefix operator %q is already defined", operator))
+	}
+	p.prefixParseFns[tt] = p.parsePrefixExpression
+
+	return nil
+}
+
+// RegisterInfix adds a binary operator parsed into an ast.InfixExpression, e.g.
+// RegisterInfix("in", EQUALS, LEFT). precedence is one of the levels above LOWEST.
+func (p *Parser) RegisterInfix(operator string, precedence int, assoc Associativity) error {
+	if precedence <= LOWEST || precedence > INDEX {
+		return errors.New(fmt.Sprintf("invalid precedence %d for operator %q", precedence, operator))
+	}
+	if assoc != LEFT && assoc != RIGHT {
+		return errors.New(fmt.Sprintf("invalid associativity %d for operator %q", assoc, operator))
+	}
+	tt, err := p.registerOperator(operator)
+	if err != nil {
+		return err
+	}
+	if _, exist := p.infixParseFns[tt]; exist {
+		return errors.New(fmt.Sprintf("infix operator %q is already defined", operator))
+	}
+	p.infixParseFns[tt] = p.parseInfixExpression
+	p.precedences[tt] = precedence
+	p.associativities[tt] = assoc
+
+	return nil
+}
+
+// registerOperator returns the token type operator is lexed as, teaching the lexer about
+// it unless it is already a prefix or infix operator, e.g. ~ may be given an infix form.
+// Operators added this way get token types of their own, prefixed with "OP:", so that
+// registering e.g. "INT" cannot change how integer literals parse.
+func (p *Parser) registerOperator(operator string) (token.TokenType, error) {
+	for _, tt := range []token.TokenType{builtinOperator(operator), token.TokenType("OP:" + operator)} {
+		_, isPrefix := p.prefixParseFns[tt]
+		_, isInfix := p.infixParseFns[tt]
+		if isPrefix || isInfix {
+			return tt, nil
+		}
+	}
+	tt := token.TokenType("OP:" + operator)
+	if err := p.l.RegisterOperator(operator, tt); err != nil {
+		return "", errors.New(fmt.Sprintf("cannot register operator %q: %s", operator, err))
+	}
+
+	return tt, nil
+}
+
+// builtinOperator returns the token type of the built-in operator spelt literal, "" if there
+// is none
+func builtinOperator(literal string) token.TokenType {
+	switch len(literal) {
+	case 1:
+		return token.SingleToken[literal[0]]
+	case 2:
+		return token.DoubleToken[literal]
+	case 3:
+		return token.TripleToken[literal]
+	}
+
+	return ""
+}
+
 func (p *Parser) ParseProgram() *ast.Program {
 	prog := new(ast.Program)
 	prog.Statements = make([]ast.Statement, 0)
 	var tkn *token.Token
 	var nxtErr error
 
-	for tkn, nxtErr = p.nextToken(); tkn.Type != token.EOF; tkn, nxtErr = p.nextToken() {
+	for tkn, nxtErr = p.nextToken(); nxtErr != nil || tkn.Type != token.EOF; tkn, nxtErr = p.nextToken() {
 		if nxtErr != nil {
 			p.Errors = append(p.Errors, nxtErr)
 			break
@@ -91,10 +219,24 @@ func (p *Parser) ParseProgram() *ast.Program {
 }
 
 func (p *Parser) parseIdentifier(tkn *token.Token) (ast.Expression, error) {
-	return &ast.Identifier{
+	ident := &ast.Identifier{
 		Token: tkn,
 		Value: tkn.Literal,
-	}, nil
+	}
+
+	// single parameter arrow function, e.g. x => x * 2
+	if !p.noBareArrow && p.assertPeek(token.ARROW) == nil {
+		fn := &ast.FunctionLiteral{
+			Token:      tkn,
+			Parameters: []*ast.Identifier{ident},
+			Defaults:   []ast.Expression{nil},
+			ParamTypes: []ast.TypeExpr{nil},
+			Arrow:      true,
+		}
+		return p.parseArrowBody(fn)
+	}
+
+	return ident, nil
 }
 
 func (p *Parser) parseIntegerLiteral(tkn *token.Token) (ast.Expression, error) {
@@ -103,6 +245,15 @@ func (p *Parser) parseIntegerLiteral(tkn *token.Token) (ast.Expression, error) {
 	}
 	var err error
 	exp.Value, err = strconv.ParseInt(tkn.Literal, 10, 64)
+	if errors.Is(err, strconv.ErrRange) {
+		// too large for an int64, fall back to arbitrary precision
+		var ok bool
+		exp.Value = 0
+		if exp.Big, ok = new(big.Int).SetString(tkn.Literal, 10); !ok {
+			return nil, err
+		}
+		return exp, nil
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -110,6 +261,10 @@ func (p *Parser) parseIntegerLiteral(tkn *token.Token) (ast.Expression, error) {
 	return exp, nil
 }
 
+func (p *Parser) parseStringLiteral(tkn *token.Token) (ast.Expression, error) {
+	return &ast.StringLiteral{Token: tkn, Value: tkn.Literal}, nil
+}
+
 func (p *Parser) parseBooleanLiteral(tkn *token.Token) (ast.Expression, error) {
 	exp := &ast.Boolean{
 		Token: tkn,
@@ -123,6 +278,10 @@ func (p *Parser) parseBooleanLiteral(tkn *token.Token) (ast.Expression, error) {
 	return exp, nil
 }
 
+func (p *Parser) parseNullLiteral(tkn *token.Token) (ast.Expression, error) {
+	return &ast.Null{Token: tkn}, nil
+}
+
 func (p *Parser) parseFunctionLiteral(tkn *token.Token) (ast.Expression, error) {
 	fn := &ast.FunctionLiteral{Token: tkn}
 
@@ -130,18 +289,21 @@ func (p *Parser) parseFunctionLiteral(tkn *token.Token) (ast.Expression, error)
 		return nil, err
 	}
 
-	params, err := p.parseFunctionParams()
-	if err != nil {
+	if err := p.parseFunctionParams(fn); err != nil {
+		return nil, err
+	}
+	if err := p.parseReturnType(fn); err != nil {
 		return nil, err
 	}
-	fn.Parameters = params
 
 	lBraceTkn, err := p.assertAndAdvanceTkn(token.LBRACE)
 	if err != nil {
 		return nil, err
 	}
 
+	p.fnDepth++
 	blockStmt, err := p.parseBlockStatement(lBraceTkn)
+	p.fnDepth--
 	if err != nil {
 		return nil, err
 	}
@@ -150,19 +312,91 @@ func (p *Parser) parseFunctionLiteral(tkn *token.Token) (ast.Expression, error)
 	return fn, nil
 }
 
-func (p *Parser) parseFunctionParams() ([]*ast.Identifier, error) {
-	idents := make([]*ast.Identifier, 0)
+func (p *Parser) parseMacroLiteral(tkn *token.Token) (ast.Expression, error) {
+	if _, err := p.assertAndAdvanceTkn(token.LPAREN); err != nil {
+		return nil, err
+	}
+
+	// macros share the function parameter grammar, but only take plain identifiers
+	fn := &ast.FunctionLiteral{Token: tkn}
+	if err := p.parseFunctionParams(fn); err != nil {
+		return nil, err
+	}
+	if min, max := fn.Arity(); min != max {
+		return nil, p.signatureErr(fn, "default and variadic parameters are not supported")
+	}
+	for _, t := range fn.ParamTypes {
+		if t != nil {
+			return nil, p.signatureErr(fn, "type annotations are not supported")
+		}
+	}
+	macro := &ast.MacroLiteral{Token: tkn, Parameters: fn.Parameters}
+
+	lBraceTkn, err := p.assertAndAdvanceTkn(token.LBRACE)
+	if err != nil {
+		return nil, err
+	}
+	if macro.Body, err = p.parseBlockStatement(lBraceTkn); err != nil {
+		return nil, err
+	}
+
+	return macro, nil
+}
+
+func (p *Parser) parseFunctionParams(fn *ast.FunctionLiteral) error {
+	fn.Parameters = make([]*ast.Identifier, 0)
+	fn.Defaults = make([]ast.Expression, 0)
+	fn.ParamTypes = make([]ast.TypeExpr, 0)
+	seen := make(map[string]bool)
+	seenDefault := false
+
 	// scan till rbrace
 	for p.assertPeek(token.RPAREN) != nil {
-		nxtToken, err := p.nextToken()
+		_, restErr := p.assertAndAdvanceTkn(token.ELLIPSIS)
+		nxtToken, err := p.assertAndAdvanceTkn(token.IDENT)
 		if err != nil {
-			return nil, err
+			return err
 		}
-
-		idents = append(idents, &ast.Identifier{
+		ident := &ast.Identifier{
 			Token: nxtToken,
 			Value: nxtToken.Literal,
-		})
+		}
+		if seen[ident.Value] {
+			return p.signatureErr(fn, fmt.Sprintf("duplicate parameter %s", ident.Value))
+		}
+		seen[ident.Value] = true
+
+		if restErr == nil {
+			fn.Rest = ident
+			if p.assertPeek(token.RPAREN) != nil {
+				return p.signatureErr(fn, fmt.Sprintf("variadic parameter ...%s must be the last parameter", ident.Value))
+			}
+			break
+		}
+
+		var typ ast.TypeExpr
+		if _, err := p.assertAndAdvanceTkn(token.COLON); err == nil {
+			if typ, err = p.parseType(); err != nil {
+				return err
+			}
+		}
+
+		var def ast.Expression
+		if _, err := p.assertAndAdvanceTkn(token.ASSIGN); err == nil {
+			defTkn, err := p.nextToken()
+			if err != nil {
+				return err
+			}
+			if def, err = p.parseExpression(defTkn, LOWEST); err != nil {
+				return err
+			}
+			seenDefault = true
+		} else if seenDefault {
+			return p.signatureErr(fn, fmt.Sprintf("parameter %s without a default follows a parameter with a default", ident.Value))
+		}
+		fn.Parameters = append(fn.Parameters, ident)
+		fn.Defaults = append(fn.Defaults, def)
+		fn.ParamTypes = append(fn.ParamTypes, typ)
 
 		// assert and skip the comma between identifiers
 		if _, err := p.assertAndAdvanceTkn(token.COMMA); err != nil {
@@ -171,10 +405,39 @@ func (p *Parser) parseFunctionParams() ([]*ast.Identifier, error) {
 	}
 
 	if _, err := p.assertAndAdvanceTkn(token.RPAREN); err != nil {
-		return nil, err
+		return err
+	}
+
+	return nil
+}
+
+// parseReturnType parses the optional return type following a parameter list, e.g. -> int
+func (p *Parser) parseReturnType(fn *ast.FunctionLiteral) error {
+	if _, err := p.assertAndAdvanceTkn(token.THINARROW); err != nil {
+		return nil
+	}
+	var err error
+	fn.ReturnType, err = p.parseType()
+
+	return err
+}
+
+// signatureErr reports a malformed parameter list along with the signature parsed so far
+func (p *Parser) signatureErr(fn *ast.FunctionLiteral, msg string) error {
+	keyword := "fn"
+	if fn.Token.Type == token.MACRO {
+		keyword = fn.Token.Literal
 	}
 
-	return idents, nil
+	return errors.New(fmt.Sprintf(
+		"%s line: %d col: %d %s in %s%s",
+		p.l.FileName,
+		p.l.LineNum,
+		p.l.LinePos,
+		msg,
+		keyword,
+		fn.Signature(),
+	))
 }
 
 func (p *Parser) parsePrefixExpression(tkn *token.Token) (ast.Expression, error) {
@@ -197,7 +460,13 @@ func (p *Parser) parsePrefixExpression(tkn *token.Token) (ast.Expression, error)
 	return expression, nil
 }
 
-func (p *Parser) parseGroupedExpression(_ *token.Token) (ast.Expression, error) {
+func (p *Parser) parseGroupedExpression(tkn *token.Token) (ast.Expression, error) {
+	// a parenthesised parameter list followed by '=>' is an arrow function, not a grouping
+	if fn, ok := p.tryParseArrowParams(tkn); ok {
+		return p.parseArrowBody(fn)
+	}
+	defer p.allowBareArrow()()
+
 	nxtToken, err := p.nextToken()
 	if err != nil {
 		return nil, err
@@ -215,6 +484,76 @@ func (p *Parser) parseGroupedExpression(_ *token.Token) (ast.Expression, error)
 	return exp, nil
 }
 
+// allowBareArrow re-enables 'x => ...' arrow functions inside a nested delimiter, returning
+// a func restoring the enclosing setting
+func (p *Parser) allowBareArrow() func() {
+	outer := p.noBareArrow
+	p.noBareArrow = false
+	return func() { p.noBareArrow = outer }
+}
+
+// tryParseArrowParams attempts to read an arrow function parameter list, rewinding the lexer
+// if what follows the '(' turns out not to be one.
+func (p *Parser) tryParseArrowParams(tkn *token.Token) (*ast.FunctionLiteral, bool) {
+	state := p.l.Save()
+	attempt := arrowAttempt{state: state, noBareArrow: p.noBareArrow}
+	if p.notArrowParams[attempt] {
+		return nil, false
+	}
+	fn := &ast.FunctionLiteral{Token: tkn, Arrow: true}
+	if err := p.parseFunctionParams(fn); err == nil && p.parseReturnType(fn) == nil && p.assertPeek(token.ARROW) == nil {
+		return fn, true
+	}
+	p.l.Restore(state)
+	if p.notArrowParams == nil {
+		p.notArrowParams = make(map[arrowAttempt]bool)
+	}
+	p.notArrowParams[attempt] = true
+
+	return nil, false
+}
+
+func (p *Parser) parseArrowBody(fn *ast.FunctionLiteral) (ast.Expression, error) {
+	// advance past '=>'
+	if _, err := p.assertAndAdvanceTkn(token.ARROW); err != nil {
+		return nil, err
+	}
+
+	p.fnDepth++
+	body, err := p.parseConciseBody()
+	p.fnDepth--
+	if err != nil {
+		return nil, err
+	}
+	fn.Body = body
+
+	return fn, nil
+}
+
+// parseConciseBody parses either a block, or a single expression wrapped into a block
+// whose token is the expression's first token rather than '{'.
+func (p *Parser) parseConciseBody() (*ast.BlockStatement, error) {
+	if lBraceTkn, err := p.assertAndAdvanceTkn(token.LBRACE); err == nil {
+		return p.parseBlockStatement(lBraceTkn)
+	}
+
+	nxtToken, err := p.nextToken()
+	if err != nil {
+		return nil, err
+	}
+	exp, err := p.parseExpression(nxtToken, LOWEST)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ast.BlockStatement{
+		Token: nxtToken,
+		Statements: []ast.Statement{
+			&ast.ExpressionStatement{Token: nxtToken, Expression: exp},
+		},
+	}, nil
+}
+
 func (p *Parser) parseIfExpression(tkn *token.Token) (ast.Expression, error) {
 	exp := &ast.IfExpression{Token: tkn}
 
@@ -266,11 +605,67 @@ func (p *Parser) parseIfExpression(tkn *token.Token) (ast.Expression, error) {
 	return exp, nil
 }
 
+func (p *Parser) parseTryExpression(tkn *token.Token) (ast.Expression, error) {
+	exp := &ast.TryExpression{Token: tkn}
+
+	lBraceTkn, err := p.assertAndAdvanceTkn(token.LBRACE)
+	if err != nil {
+		return nil, err
+	}
+	if exp.Block, err = p.parseBlockStatement(lBraceTkn); err != nil {
+		return nil, err
+	}
+
+	if _, err := p.assertAndAdvanceTkn(token.CATCH); err == nil {
+		if _, err := p.assertAndAdvanceTkn(token.LPAREN); err != nil {
+			return nil, err
+		}
+		paramTkn, err := p.assertAndAdvanceTkn(token.IDENT)
+		if err != nil {
+			return nil, err
+		}
+		exp.CatchParam = &ast.Identifier{Token: paramTkn, Value: paramTkn.Literal}
+		if _, err := p.assertAndAdvanceTkn(token.RPAREN); err != nil {
+			return nil, err
+		}
+		lBraceTkn, err := p.assertAndAdvanceTkn(token.LBRACE)
+		if err != nil {
+			return nil, err
+		}
+		if exp.Catch, err = p.parseBlockStatement(lBraceTkn); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := p.assertAndAdvanceTkn(token.FINALLY); err == nil {
+		lBraceTkn, err := p.assertAndAdvanceTkn(token.LBRACE)
+		if err != nil {
+			return nil, err
+		}
+		if exp.Finally, err = p.parseBlockStatement(lBraceTkn); err != nil {
+			return nil, err
+		}
+	}
+
+	if exp.Catch == nil && exp.Finally == nil {
+		return nil, errors.New(fmt.Sprintf(
+			"%s line: %d col: %d try must be followed by a catch or finally clause",
+			p.l.FileName,
+			p.l.LineNum,
+			p.l.LinePos,
+		))
+	}
+
+	return exp, nil
+}
+
 func (p *Parser) parseBlockStatement(tkn *token.Token) (*ast.BlockStatement, error) {
 	block := &ast.BlockStatement{
 		Token:      tkn,
 		Statements: make([]ast.Statement, 0),
 	}
+	p.blockDepth++
+	defer func() { p.blockDepth-- }()
 
 	for p.assertPeek(token.RBRACE, token.EOF) != nil {
 		nxtToken, err := p.nextToken()
@@ -284,9 +679,11 @@ func (p *Parser) parseBlockStatement(tkn *token.Token) (*ast.BlockStatement, err
 		block.Statements = append(block.Statements, stmt)
 	}
 	// advance past '}'
-	if _, err := p.assertAndAdvanceTkn(token.RBRACE); err != nil {
+	rBraceTkn, err := p.assertAndAdvanceTkn(token.RBRACE)
+	if err != nil {
 		return nil, err
 	}
+	block.Rbrace = rBraceTkn
 
 	return block, nil
 }
@@ -299,6 +696,11 @@ func (p *Parser) parseInfixExpression(left ast.Expression, tkn *token.Token) (as
 	}
 
 	pred := p.getPrecedence(tkn)
+	// parsing the right side one level lower lets an operator of the same precedence
+	// continue the right side, making it right associative
+	if p.getAssociativity(tkn) == RIGHT {
+		pred--
+	}
 	nxtToken, err := p.nextToken()
 	if err != nil {
 		return nil, err
@@ -326,8 +728,47 @@ func (p *Parser) parseCallExpression(fn ast.Expression, tkn *token.Token) (ast.E
 	return exp, nil
 }
 
+func (p *Parser) parseMemberExpression(obj ast.Expression, tkn *token.Token) (ast.Expression, error) {
+	propTkn, err := p.assertAndAdvanceTkn(token.IDENT)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ast.MemberExpression{
+		Token:    tkn,
+		Object:   obj,
+		Property: &ast.Identifier{Token: propTkn, Value: propTkn.Literal},
+		Optional: tkn.Type == token.OPTDOT,
+	}, nil
+}
+
+func (p *Parser) parseIndexExpression(left ast.Expression, tkn *token.Token) (ast.Expression, error) {
+	defer p.allowBareArrow()()
+	exp := &ast.IndexExpression{
+		Token:    tkn,
+		Left:     left,
+		Optional: tkn.Type == token.OPTLBRACK,
+	}
+
+	nxtToken, err := p.nextToken()
+	if err != nil {
+		return nil, err
+	}
+	if exp.Index, err = p.parseExpression(nxtToken, LOWEST); err != nil {
+		return nil, err
+	}
+	// advance past ']'
+	if _, err := p.assertAndAdvanceTkn(token.RBRACKET); err != nil {
+		return nil, err
+	}
+
+	return exp, nil
+}
+
 func (p *Parser) parseCallArgs() ([]ast.Expression, error) {
+	defer p.allowBareArrow()()
 	args := make([]ast.Expression, 0)
+	named := make(map[string]bool)
 
 	// scan till rbrace
 	for p.assertPeek(token.RPAREN) != nil {
@@ -342,10 +783,28 @@ func (p *Parser) parseCallArgs() ([]ast.Expression, error) {
 		// token ',' is LOWEST, causing the loop in parseExpression to terminate early
 		// as the precedence of LOWEST is always in the worst case equal to the caller.
 		// This way, the expression will always be evaluated up till the ',' OR ')' OR EOF token.
-		arg, err := p.parseExpression(nxtToken, LOWEST)
+		var arg ast.Expression
+		if nxtToken.Type == token.ELLIPSIS {
+			arg, err = p.parseSpreadExpression(nxtToken)
+		} else {
+			arg, err = p.parseExpression(nxtToken, LOWEST)
+		}
 		if err != nil {
 			return nil, err
 		}
+
+		// an identifier followed by ':' names the parameter the argument binds to
+		if ident, ok := arg.(*ast.Identifier); ok && p.assertPeek(token.COLON) == nil {
+			if arg, err = p.parseNamedArgument(ident); err != nil {
+				return nil, err
+			}
+			if named[ident.Value] {
+				return nil, p.callArgErr(fmt.Sprintf("duplicate named argument %s", ident.Value))
+			}
+			named[ident.Value] = true
+		} else if len(named) != 0 {
+			return nil, p.callArgErr(fmt.Sprintf("positional argument %s follows named arguments", arg.String()))
+		}
 		args = append(args, arg)
 
 		// assert and skip the comma between expressions
@@ -361,12 +820,60 @@ func (p *Parser) parseCallArgs() ([]ast.Expression, error) {
 	return args, nil
 }
 
+func (p *Parser) parseNamedArgument(name *ast.Identifier) (ast.Expression, error) {
+	// advance past ':'
+	if _, err := p.assertAndAdvanceTkn(token.COLON); err != nil {
+		return nil, err
+	}
+	nxtToken, err := p.nextToken()
+	if err != nil {
+		return nil, err
+	}
+	val, err := p.parseExpression(nxtToken, LOWEST)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ast.NamedArgument{Token: name.Token, Name: name, Value: val}, nil
+}
+
+func (p *Parser) callArgErr(msg string) error {
+	return errors.New(fmt.Sprintf(
+		"%s line: %d col: %d %s",
+		p.l.FileName,
+		p.l.LineNum,
+		p.l.LinePos,
+		msg,
+	))
+}
+
+func (p *Parser) parseSpreadExpression(tkn *token.Token) (ast.Expression, error) {
+	nxtToken, err := p.nextToken()
+	if err != nil {
+		return nil, err
+	}
+	val, err := p.parseExpression(nxtToken, LOWEST)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ast.SpreadExpression{Token: tkn, Value: val}, nil
+}
+
 func (p *Parser) parseStatement(startToken *token.Token) (ast.Statement, error) {
 	switch startToken.Type {
 	case token.LET:
 		return p.parseLetStatement(startToken)
 	case token.RETURN:
 		return p.parseReturnStatement(startToken)
+	case token.THROW:
+		return p.parseThrowStatement(startToken)
+	case token.DEFER:
+		return p.parseDeferStatement(startToken)
+	case token.IMPORT:
+		return p.parseImportStatement(startToken)
+	case token.EXPORT:
+		return p.parseExportStatement(startToken)
 	default:
 		return p.parseExpressionStatement(startToken)
 	}
@@ -377,13 +884,24 @@ func (p *Parser) parseLetStatement(startToken *token.Token) (*ast.LetStatement,
 		Token: startToken,
 	}
 
-	nameToken, err := p.assertAndAdvanceTkn(token.IDENT)
+	nameToken, err := p.assertAndAdvanceTkn(token.IDENT, token.LBRACKET, token.LBRACE)
 	if err != nil {
 		return nil, err
 	}
-	stmt.Name = &ast.Identifier{
-		Token: nameToken,
-		Value: nameToken.Literal,
+	if nameToken.Type == token.IDENT {
+		stmt.Name = &ast.Identifier{
+			Token: nameToken,
+			Value: nameToken.Literal,
+		}
+	} else if stmt.Pattern, err = p.parseBindingPattern(nameToken); err != nil {
+		return nil, err
+	}
+	if stmt.Name != nil {
+		if _, err := p.assertAndAdvanceTkn(token.COLON); err == nil {
+			if stmt.Type, err = p.parseType(); err != nil {
+				return nil, err
+			}
+		}
 	}
 
 	if _, err := p.assertAndAdvanceTkn(token.ASSIGN); err != nil {
@@ -395,6 +913,9 @@ func (p *Parser) parseLetStatement(startToken *token.Token) (*ast.LetStatement,
 		return nil, err
 	}
 	exp, err := p.parseExpression(nxtTkn, LOWEST)
+	if err != nil {
+		return nil, err
+	}
 	stmt.Value = exp
 
 	// assert is semicolon
@@ -428,6 +949,130 @@ func (p *Parser) parseReturnStatement(startToken *token.Token) (*ast.ReturnState
 	return stmt, nil
 }
 
+func (p *Parser) parseThrowStatement(startToken *token.Token) (*ast.ThrowStatement, error) {
+	stmt := &ast.ThrowStatement{
+		Token: startToken,
+	}
+
+	nxtTkn, err := p.nextToken()
+	if err != nil {
+		return nil, err
+	}
+	if stmt.Value, err = p.parseExpression(nxtTkn, LOWEST); err != nil {
+		return nil, err
+	}
+
+	// assert is semicolon
+	if _, err := p.assertAndAdvanceTkn(token.SEMICOLON); err != nil {
+		return nil, err
+	}
+
+	return stmt, nil
+}
+
+func (p *Parser) parseDeferStatement(startToken *token.Token) (*ast.DeferStatement, error) {
+	if p.fnDepth == 0 {
+		return nil, errors.New(fmt.Sprintf(
+			"%s line: %d col: %d defer is only allowed inside a function body",
+			p.l.FileName,
+			p.l.LineNum,
+			p.l.LinePos,
+		))
+	}
+	stmt := &ast.DeferStatement{
+		Token: startToken,
+	}
+
+	nxtTkn, err := p.nextToken()
+	if err != nil {
+		return nil, err
+	}
+	exp, err := p.parseExpression(nxtTkn, LOWEST)
+	if err != nil {
+		return nil, err
+	}
+	call, ok := exp.(*ast.CallExpression)
+	if !ok {
+		return nil, errors.New(fmt.Sprintf(
+			"%s line: %d col: %d expression in defer must be a function call, got %s",
+			p.l.FileName,
+			p.l.LineNum,
+			p.l.LinePos,
+			exp.String(),
+		))
+	}
+	stmt.Call = call
+
+	// assert is semicolon
+	if _, err := p.assertAndAdvanceTkn(token.SEMICOLON); err != nil {
+		return nil, err
+	}
+
+	return stmt, nil
+}
+
+func (p *Parser) parseImportStatement(startToken *token.Token) (*ast.ImportStatement, error) {
+	if err := p.assertTopLevel(startToken); err != nil {
+		return nil, err
+	}
+	stmt := &ast.ImportStatement{
+		Token: startToken,
+	}
+
+	pathTkn, err := p.assertAndAdvanceTkn(token.STRING)
+	if err != nil {
+		return nil, err
+	}
+	stmt.Path = &ast.StringLiteral{Token: pathTkn, Value: pathTkn.Literal}
+
+	if _, err := p.assertAndAdvanceTkn(token.AS); err != nil {
+		return nil, err
+	}
+	aliasTkn, err := p.assertAndAdvanceTkn(token.IDENT)
+	if err != nil {
+		return nil, err
+	}
+	stmt.Alias = &ast.Identifier{Token: aliasTkn, Value: aliasTkn.Literal}
+
+	// assert is semicolon
+	if _, err := p.assertAndAdvanceTkn(token.SEMICOLON); err != nil {
+		return nil, err
+	}
+
+	return stmt, nil
+}
+
+func (p *Parser) parseExportStatement(startToken *token.Token) (*ast.ExportStatement, error) {
+	if err := p.assertTopLevel(startToken); err != nil {
+		return nil, err
+	}
+
+	letTkn, err := p.assertAndAdvanceTkn(token.LET)
+	if err != nil {
+		return nil, err
+	}
+	letStmt, err := p.parseLetStatement(letTkn)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ast.ExportStatement{Token: startToken, Statement: letStmt}, nil
+}
+
+func (p *Parser) assertTopLevel(tkn *token.Token) error {
+	if p.blockDepth == 0 {
+		return nil
+	}
+
+	return errors.New(fmt.Sprintf(
+		"%s line: %d col: %d %s is only allowed at the top level of a module",
+		p.l.FileName,
+		p.l.LineNum,
+		p.l.LinePos,
+		tkn.Literal,
+	))
+}
+
 func (p *Parser) parseExpressionStatement(startToken *token.Token) (*ast.ExpressionStatement, error) {
 	exp, err := p.parseExpression(startToken, LOWEST)
 	if err != nil {
@@ -445,19 +1090,59 @@ func (p *Parser) parseExpressionStatement(startToken *token.Token) (*ast.Express
 }
 
 var precedences = map[token.TokenType]int{
-	token.EQ:       EQUALS,
-	token.NEQ:      EQUALS,
-	token.LT:       LESSGREATER,
-	token.GT:       LESSGREATER,
-	token.PLUS:     SUM,
-	token.MINUS:    SUM,
-	token.SLASH:    PRODUCT,
-	token.ASTERISK: PRODUCT,
-	token.LPAREN:   CALL,
+	token.EQ:        EQUALS,
+	token.NEQ:       EQUALS,
+	token.LT:        LESSGREATER,
+	token.GT:        LESSGREATER,
+	token.PLUS:      SUM,
+	token.MINUS:     SUM,
+	token.SLASH:     PRODUCT,
+	token.ASTERISK:  PRODUCT,
+	token.PERCENT:   PRODUCT,
+	token.POWER:     POWER,
+	token.AMPERSAND: BITAND,
+	token.PIPE:      BITOR,
+	token.CARET:     BITXOR,
+	token.LSHIFT:    SHIFT,
+	token.RSHIFT:    SHIFT,
+	token.LPAREN:    CALL,
+	token.NULLISH:   NULLISH,
+	token.DOT:       INDEX,
+	token.OPTDOT:    INDEX,
+	token.LBRACKET:  INDEX,
+	token.OPTLBRACK: INDEX,
+}
+
+type Associativity int
+
+const (
+	LEFT Associativity = iota
+	RIGHT
+)
+
+// associativities lists the infix operators that do not associate to the left,
+// e.g. 2 ** 3 ** 2 is (2 ** (3 ** 2))
+var associativities = map[token.TokenType]Associativity{
+	token.POWER: RIGHT,
+}
+
+// InfixPrecedence returns the default precedence and associativity of a token in infix
+// position, ok is false if it has none. Operators registered on a Parser are not included.
+func InfixPrecedence(tt token.TokenType) (pred int, assoc Associativity, ok bool) {
+	pred, ok = precedences[tt]
+	return pred, associativities[tt], ok
+}
+
+func (p *Parser) getAssociativity(tkn *token.Token) Associativity {
+	if a, exist := p.associativities[tkn.Type]; exist {
+		return a
+	}
+
+	return LEFT
 }
 
 func (p *Parser) getPrecedence(tkn *token.Token) int {
-	if p, exist := precedences[tkn.Type]; exist {
+	if p, exist := p.precedences[tkn.Type]; exist {
 		return p
 	}
 
@@ -468,7 +1153,10 @@ func (p *Parser) parseExpression(startToken *token.Token, precedence int) (ast.E
 	prefix, exist := p.prefixParseFns[startToken.Type]
 	if !exist {
 		return nil, errors.New(fmt.Sprintf(
-			"no prefix parse function for %s found",
+			"%s line: %d col: %d no prefix parse function for %s found",
+			p.l.FileName,
+			startToken.Line,
+			startToken.Column,
 			startToken.Literal,
 		))
 	}
@@ -495,7 +1183,10 @@ func (p *Parser) parseExpression(startToken *token.Token, precedence int) (ast.E
 		infix, exist := p.infixParseFns[nxtToken.Type]
 		if !exist {
 			return nil, errors.New(fmt.Sprintf(
-				"no infix parse function for %s found",
+				"%s line: %d col: %d no infix parse function for %s found",
+				p.l.FileName,
+				nxtToken.Line,
+				nxtToken.Column,
 				nxtToken.Literal,
 			))
 		}
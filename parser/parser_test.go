@@ -2,6 +2,11 @@ package parser
 
 import (
 	"fmt"
+	goast "go/ast"
+	goparser "go/parser"
+	gotoken "go/token"
+	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/geraldywy/monkey/ast"
@@ -177,6 +182,7 @@ func TestParsingPrefixExpressions(t *testing.T) {
 		{"-foobar;", "-", "foobar"},
 		{"!true;", "!", true},
 		{"!false;", "!", false},
+		{"~5;", "~", 5},
 	}
 
 	for _, tt := range prefixTests {
@@ -244,6 +250,13 @@ func TestParsingInfixExpressions(t *testing.T) {
 		{"5 < 5;", 5, "<", 5},
 		{"5 == 5;", 5, "==", 5},
 		{"5 != 5;", 5, "!=", 5},
+		{"5 % 5;", 5, "%", 5},
+		{"5 ** 5;", 5, "**", 5},
+		{"5 & 5;", 5, "&", 5},
+		{"5 | 5;", 5, "|", 5},
+		{"5 ^ 5;", 5, "^", 5},
+		{"5 << 5;", 5, "<<", 5},
+		{"5 >> 5;", 5, ">>", 5},
 		{"foobar + barfoo;", "foobar", "+", "barfoo"},
 		{"foobar - barfoo;", "foobar", "-", "barfoo"},
 		{"foobar * barfoo;", "foobar", "*", "barfoo"},
@@ -318,7 +331,7 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 		},
 		{
 			"3 + 4; -5 * 5",
-			"(3 + 4)((-5) * 5)",
+			"(3 + 4);((-5) * 5)",
 		},
 		{
 			"3 + 4 - 5 * 5",
@@ -395,6 +408,46 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 			"add(a + b + c * d / f + g)",
 			"add((((a + b) + ((c * d) / f)) + g))",
 		},
+		{
+			"2 ** 3 ** 2",
+			"(2 ** (3 ** 2))",
+		},
+		{
+			"2 * 3 ** 2",
+			"(2 * (3 ** 2))",
+		},
+		{
+			"-2 ** 2",
+			"(-(2 ** 2))",
+		},
+		{
+			"2 ** -1",
+			"(2 ** (-1))",
+		},
+		{
+			"a % b * c",
+			"((a % b) * c)",
+		},
+		{
+			"a + b << c - d",
+			"((a + b) << (c - d))",
+		},
+		{
+			"a << b < c >> d",
+			"((a << b) < (c >> d))",
+		},
+		{
+			"a | b ^ c & d == e",
+			"(a | (b ^ (c & (d == e))))",
+		},
+		{
+			"a & b | c & d",
+			"((a & b) | (c & d))",
+		},
+		{
+			"~a & b",
+			"((~a) & b)",
+		},
 	}
 	for _, tt := range tests {
 		l := lexer.New(tt.input, "parser_test.go")
@@ -741,3 +794,862 @@ func testBooleanLiteral(t *testing.T, exp ast.Expression, value bool) bool {
 
 	return true
 }
+
+func TestMatchExpression(t *testing.T) {
+	input := `match (x) {
+		0 => zero,
+		-1 => negOne,
+		true => yes,
+		[] => empty,
+		[head, ...tail] if head > 0 => { head },
+		[_, [a, b]] => a,
+		{name, age: years} => years,
+		n => n,
+	}`
+	l := lexer.New(input, "parser_test.go")
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain %d statements. got=%d\n",
+			1, len(program.Statements))
+	}
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T",
+			program.Statements[0])
+	}
+	exp, ok := stmt.Expression.(*ast.MatchExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.MatchExpression. got=%T", stmt.Expression)
+	}
+	if !testIdentifier(t, exp.Subject, "x") {
+		return
+	}
+
+	tests := []struct {
+		pattern string
+		guard   string
+		body    string
+	}{
+		{"0", "", "{ zero }"},
+		{"-1", "", "{ negOne }"},
+		{"true", "", "{ yes }"},
+		{"[]", "", "{ empty }"},
+		{"[head, ...tail]", "(head > 0)", "{ head }"},
+		{"[_, [a, b]]", "", "{ a }"},
+		{"{name, age: years}", "", "{ years }"},
+		{"n", "", "{ n }"},
+	}
+	if len(exp.Arms) != len(tests) {
+		t.Fatalf("wrong number of arms. want=%d, got=%d", len(tests), len(exp.Arms))
+	}
+	for i, tt := range tests {
+		arm := exp.Arms[i]
+		if arm.Pattern.String() != tt.pattern {
+			t.Errorf("arm %d pattern wrong. want=%q, got=%q", i, tt.pattern, arm.Pattern.String())
+		}
+		guard := ""
+		if arm.Guard != nil {
+			guard = arm.Guard.String()
+		}
+		if guard != tt.guard {
+			t.Errorf("arm %d guard wrong. want=%q, got=%q", i, tt.guard, guard)
+		}
+		if arm.Body.String() != tt.body {
+			t.Errorf("arm %d body wrong. want=%q, got=%q", i, tt.body, arm.Body.String())
+		}
+	}
+
+	if _, ok := exp.Arms[5].Pattern.(*ast.ArrayPattern).Elements[0].(*ast.WildcardPattern); !ok {
+		t.Errorf("arm 5 first element is not ast.WildcardPattern. got=%T",
+			exp.Arms[5].Pattern.(*ast.ArrayPattern).Elements[0])
+	}
+	if rest := exp.Arms[4].Pattern.(*ast.ArrayPattern).Rest; rest == nil || rest.Value != "tail" {
+		t.Errorf("arm 4 rest is not tail. got=%v", rest)
+	}
+}
+
+func TestMatchPatternErrors(t *testing.T) {
+	tests := []string{
+		"match (x) { [...a, b] => a }",
+		"match (x) { {a, a} => a }",
+		"match (x) { a + 1 => a }",
+		"match (x) { fn => a }",
+	}
+	for _, input := range tests {
+		l := lexer.New(input, "parser_test.go")
+		p := New(l)
+		p.ParseProgram()
+		if len(p.Errors) == 0 {
+			t.Errorf("expected parser errors for %q, got none", input)
+		}
+	}
+}
+
+func TestDestructuringLetStatements(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedPattern string
+		expectedNames   []string
+	}{
+		{"let [a, b] = xs;", "[a, b]", []string{"a", "b"}},
+		{"let [a, b, ...rest] = xs;", "[a, b, ...rest]", []string{"a", "b", "rest"}},
+		{"let [_, second] = xs;", "[_, second]", []string{"second"}},
+		{"let {name, age: years} = person;", "{name, age: years}", []string{"name", "years"}},
+		{"let {pos: [x, y], meta: {id}} = p;", "{pos: [x, y], meta: {id}}", []string{"x", "y", "id"}},
+	}
+	for _, tt := range tests {
+		l := lexer.New(tt.input, "parser_test.go")
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain 1 statements. got=%d",
+				len(program.Statements))
+		}
+		letStmt, ok := program.Statements[0].(*ast.LetStatement)
+		if !ok {
+			t.Fatalf("s not *ast.LetStatement. got=%T", program.Statements[0])
+		}
+		if letStmt.Name != nil {
+			t.Errorf("letStmt.Name not nil for destructuring let. got=%s", letStmt.Name)
+		}
+		if letStmt.Pattern.String() != tt.expectedPattern {
+			t.Errorf("letStmt.Pattern wrong. want=%q, got=%q", tt.expectedPattern, letStmt.Pattern.String())
+		}
+		names := []string{}
+		for _, ident := range ast.Bindings(letStmt.Pattern) {
+			names = append(names, ident.Value)
+		}
+		if fmt.Sprint(names) != fmt.Sprint(tt.expectedNames) {
+			t.Errorf("bound names wrong. want=%v, got=%v", tt.expectedNames, names)
+		}
+	}
+}
+
+func TestDestructuringLetErrors(t *testing.T) {
+	tests := []string{
+		"let [a, a] = xs;",
+		"let {a, b: a} = xs;",
+		"let [1, b] = xs;",
+		"let {a: true} = xs;",
+		"let [...a, b] = xs;",
+	}
+	for _, input := range tests {
+		l := lexer.New(input, "parser_test.go")
+		p := New(l)
+		p.ParseProgram()
+		if len(p.Errors) == 0 {
+			t.Errorf("expected parser errors for %q, got none", input)
+		}
+	}
+}
+
+func TestFunctionDefaultAndVariadicParameters(t *testing.T) {
+	tests := []struct {
+		input             string
+		expectedSignature string
+		expectedMin       int
+		expectedMax       int
+	}{
+		{"fn(a, b) {}", "(a, b)", 2, 2},
+		{"fn(a, b = 10) {}", "(a, b = 10)", 1, 2},
+		{"fn(a = 1 + 2, b = a) {}", "(a = (1 + 2), b = a)", 0, 2},
+		{"fn(a, b = 10, ...rest) {}", "(a, b = 10, ...rest)", 1, -1},
+		{"fn(...rest) {}", "(...rest)", 0, -1},
+	}
+	for _, tt := range tests {
+		l := lexer.New(tt.input, "parser_test.go")
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		function, ok := stmt.Expression.(*ast.FunctionLiteral)
+		if !ok {
+			t.Fatalf("stmt.Expression is not ast.FunctionLiteral. got=%T", stmt.Expression)
+		}
+		if function.Signature() != tt.expectedSignature {
+			t.Errorf("signature wrong. want=%q, got=%q", tt.expectedSignature, function.Signature())
+		}
+		min, max := function.Arity()
+		if min != tt.expectedMin || max != tt.expectedMax {
+			t.Errorf("arity wrong for %q. want=(%d, %d), got=(%d, %d)",
+				tt.input, tt.expectedMin, tt.expectedMax, min, max)
+		}
+	}
+}
+
+func TestFunctionParameterErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string // the first error, line:col message
+	}{
+		{"fn(a = 1, b) {}", "1:11 parameter b without a default follows a parameter with a default in fn(a = 1)"},
+		{"fn(...rest, a) {}", "1:10 variadic parameter ...rest must be the last parameter in fn(...rest)"},
+		{"fn(a, a) {}", "1:7 duplicate parameter a in fn(a)"},
+		{"fn(1) {}", "1:3 expected one of tokens: [IDENT], got INT"},
+		{"let f = fn(a = 1, b) { a };", "1:19 parameter b without a default follows a parameter with a default in fn(a = 1)"},
+		{"let f = fn(...rest, a) { a };", "1:18 variadic parameter ...rest must be the last parameter in fn(...rest)"},
+		{"let f = fn(a, a) { a };", "1:15 duplicate parameter a in fn(a)"},
+		{"let f = fn(1) { 1 };", "1:11 expected one of tokens: [IDENT], got INT"},
+	}
+	for _, tt := range tests {
+		l := lexer.New(tt.input, "parser_test.go")
+		p := New(l)
+		p.ParseProgram()
+		if len(p.Errors) == 0 {
+			t.Errorf("expected parser errors for %q, got none", tt.input)
+			continue
+		}
+		got := strings.TrimPrefix(p.Errors[0].Error(), "parser_test.go line: ")
+		got = strings.Replace(got, " col: ", ":", 1)
+		if got != tt.expected {
+			t.Errorf("first error for %q wrong. want=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestSpreadArgumentParsing(t *testing.T) {
+	input := "add(1, ...xs, ...f(y + 1))"
+	l := lexer.New(input, "parser_test.go")
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	exp, ok := stmt.Expression.(*ast.CallExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.CallExpression. got=%T", stmt.Expression)
+	}
+	if len(exp.Arguments) != 3 {
+		t.Fatalf("wrong length of arguments. got=%d", len(exp.Arguments))
+	}
+	testLiteralExpression(t, exp.Arguments[0], 1)
+	spread, ok := exp.Arguments[1].(*ast.SpreadExpression)
+	if !ok {
+		t.Fatalf("exp.Arguments[1] is not ast.SpreadExpression. got=%T", exp.Arguments[1])
+	}
+	testIdentifier(t, spread.Value, "xs")
+	if exp.String() != "add(1, ...xs, ...f((y + 1)))" {
+		t.Errorf("exp.String() wrong. got=%q", exp.String())
+	}
+}
+
+func TestNamedArgumentParsing(t *testing.T) {
+	input := "connect(host, port: 80, timeout: 1 + 2)"
+	l := lexer.New(input, "parser_test.go")
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	exp, ok := stmt.Expression.(*ast.CallExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.CallExpression. got=%T", stmt.Expression)
+	}
+	if len(exp.Arguments) != 3 {
+		t.Fatalf("wrong length of arguments. got=%d", len(exp.Arguments))
+	}
+	testIdentifier(t, exp.Arguments[0], "host")
+	named, ok := exp.Arguments[1].(*ast.NamedArgument)
+	if !ok {
+		t.Fatalf("exp.Arguments[1] is not ast.NamedArgument. got=%T", exp.Arguments[1])
+	}
+	if named.Name.Value != "port" {
+		t.Errorf("named.Name wrong. want=port, got=%s", named.Name.Value)
+	}
+	testLiteralExpression(t, named.Value, 80)
+	if exp.String() != "connect(host, port: 80, timeout: (1 + 2))" {
+		t.Errorf("exp.String() wrong. got=%q", exp.String())
+	}
+}
+
+func TestNamedArgumentErrors(t *testing.T) {
+	tests := []string{
+		"connect(port: 80, host)",
+		"connect(port: 80, ...rest)",
+		"connect(port: 80, port: 81)",
+	}
+	for _, input := range tests {
+		l := lexer.New(input, "parser_test.go")
+		p := New(l)
+		p.ParseProgram()
+		if len(p.Errors) == 0 {
+			t.Errorf("expected parser errors for %q, got none", input)
+		}
+	}
+}
+
+func TestArrowFunctionParsing(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedParams []string
+		expected       string
+	}{
+		{"x => x * 2", []string{"x"}, "(x) => (x * 2)"},
+		{"() => 1", []string{}, "() => 1"},
+		{"(a, b) => a + b", []string{"a", "b"}, "(a, b) => (a + b)"},
+		{"(a, b = 2, ...rest) => { a }", []string{"a", "b"}, "(a, b = 2, ...rest) => { a }"},
+		{"(x) => y => x + y", []string{"x"}, "(x) => (y) => (x + y)"},
+	}
+	for _, tt := range tests {
+		l := lexer.New(tt.input, "parser_test.go")
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		function, ok := stmt.Expression.(*ast.FunctionLiteral)
+		if !ok {
+			t.Fatalf("stmt.Expression is not ast.FunctionLiteral. got=%T", stmt.Expression)
+		}
+		if !function.Arrow {
+			t.Errorf("function.Arrow is false for %q", tt.input)
+		}
+		if len(function.Parameters) != len(tt.expectedParams) {
+			t.Fatalf("length parameters wrong. want %d, got=%d\n",
+				len(tt.expectedParams), len(function.Parameters))
+		}
+		for i, ident := range tt.expectedParams {
+			testLiteralExpression(t, function.Parameters[i], ident)
+		}
+		if program.String() != tt.expected {
+			t.Errorf("program.String() wrong. want=%q, got=%q", tt.expected, program.String())
+		}
+
+		// the printed form must parse back to the same output
+		l = lexer.New(program.String(), "parser_test.go")
+		p = New(l)
+		reparsed := p.ParseProgram()
+		checkParserErrors(t, p)
+		if reparsed.String() != tt.expected {
+			t.Errorf("reparsed String() wrong. want=%q, got=%q", tt.expected, reparsed.String())
+		}
+	}
+}
+
+func TestArrowFunctionDisambiguation(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"(a + b) * c", "((a + b) * c)"},
+		{"(a) * c", "(a * c)"},
+		{"map(xs, x => x + 1)", "map(xs, (x) => (x + 1))"},
+		{"map(xs, (x, i) => x * i)", "map(xs, (x, i) => (x * i))"},
+		{"match (x) { n if ok => n }", "match (x) { n if ok => { n } }"},
+		{"match (x) { n if any(xs, y => y) => n }", "match (x) { n if any(xs, (y) => y) => { n } }"},
+		{"(a = (b = (c = 1) => c) => b) => a", "(a = (b = (c = 1) => c) => b) => a"},
+		{"f((a = (b)) => a, (b))", "f((a = b) => a, b)"},
+		{"match (x) { a if match (y) { b if c => true } == d => 1 }", "match (x) { a if (match (y) { b if c => { true } } == d) => { 1 } }"},
+	}
+	for _, tt := range tests {
+		l := lexer.New(tt.input, "parser_test.go")
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+}
+
+func TestNullLiteralExpression(t *testing.T) {
+	input := "null;"
+	l := lexer.New(input, "parser_test.go")
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	null, ok := stmt.Expression.(*ast.Null)
+	if !ok {
+		t.Fatalf("exp not *ast.Null. got=%T", stmt.Expression)
+	}
+	if null.TokenLiteral() != "null" {
+		t.Errorf("null.TokenLiteral not %s. got=%s", "null", null.TokenLiteral())
+	}
+}
+
+func TestNullishAndOptionalChainingParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"a ?? b", "(a ?? b)"},
+		{"a ?? b ?? c", "((a ?? b) ?? c)"},
+		{"a == null ?? b == c", "((a == null) ?? (b == c))"},
+		{"a ?? b + 1", "(a ?? (b + 1))"},
+		{"a.b", "(a.b)"},
+		{"a?.b", "(a?.b)"},
+		{"a?.b.c", "((a?.b).c)"},
+		{"a[1 + 2]", "(a[(1 + 2)])"},
+		{"a?[i]?.b", "((a?[i])?.b)"},
+		{"-a?.b", "(-(a?.b))"},
+		{"a.f(1)", "(a.f)(1)"},
+		{"f(x)?.y ?? 0", "((f(x)?.y) ?? 0)"},
+	}
+	for _, tt := range tests {
+		l := lexer.New(tt.input, "parser_test.go")
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+
+	l := lexer.New("a?.b?[c]", "parser_test.go")
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+	index, ok := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.IndexExpression)
+	if !ok || !index.Optional {
+		t.Fatalf("exp not optional *ast.IndexExpression. got=%T", program.Statements[0])
+	}
+	member, ok := index.Left.(*ast.MemberExpression)
+	if !ok || !member.Optional {
+		t.Fatalf("index.Left not optional *ast.MemberExpression. got=%T", index.Left)
+	}
+}
+
+func TestBigIntegerLiteralExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"9223372036854775807", ""},
+		{"9223372036854775808", "9223372036854775808"},
+		{"123456789012345678901234567890", "123456789012345678901234567890"},
+	}
+	for _, tt := range tests {
+		l := lexer.New(tt.input, "parser_test.go")
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		literal, ok := stmt.Expression.(*ast.IntegerLiteral)
+		if !ok {
+			t.Fatalf("exp not *ast.IntegerLiteral. got=%T", stmt.Expression)
+		}
+		if tt.expected == "" {
+			if literal.Big != nil {
+				t.Errorf("literal.Big not nil for %s. got=%s", tt.input, literal.Big)
+			}
+			continue
+		}
+		if literal.Big == nil || literal.Big.String() != tt.expected {
+			t.Errorf("literal.Big not %s. got=%v", tt.expected, literal.Big)
+		}
+		if literal.String() != tt.input {
+			t.Errorf("literal.String() not %s. got=%s", tt.input, literal.String())
+		}
+	}
+}
+
+func TestThrowStatement(t *testing.T) {
+	input := "throw err(1 + 2);"
+	l := lexer.New(input, "parser_test.go")
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+	stmt, ok := program.Statements[0].(*ast.ThrowStatement)
+	if !ok {
+		t.Fatalf("stmt not *ast.ThrowStatement. got=%T", program.Statements[0])
+	}
+	if stmt.String() != "throw err((1 + 2));" {
+		t.Errorf("stmt.String() wrong. got=%q", stmt.String())
+	}
+}
+
+func TestTryExpression(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectCatch   bool
+		expectFinally bool
+		expected      string
+	}{
+		{"try { f() } catch (e) { e }", true, false, "try { f() } catch (e) { e }"},
+		{"try { f() } finally { g() }", false, true, "try { f() } finally { g() }"},
+		{
+			"try { throw 1; } catch (e) { 0 } finally { g() }", true, true,
+			"try { throw 1; } catch (e) { 0 } finally { g() }",
+		},
+	}
+	for _, tt := range tests {
+		l := lexer.New(tt.input, "parser_test.go")
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		exp, ok := stmt.Expression.(*ast.TryExpression)
+		if !ok {
+			t.Fatalf("stmt.Expression is not ast.TryExpression. got=%T", stmt.Expression)
+		}
+		if (exp.Catch != nil) != tt.expectCatch {
+			t.Errorf("exp.Catch presence wrong for %q", tt.input)
+		}
+		if tt.expectCatch && !testIdentifier(t, exp.CatchParam, "e") {
+			return
+		}
+		if (exp.Finally != nil) != tt.expectFinally {
+			t.Errorf("exp.Finally presence wrong for %q", tt.input)
+		}
+		if exp.String() != tt.expected {
+			t.Errorf("exp.String() wrong. want=%q, got=%q", tt.expected, exp.String())
+		}
+	}
+}
+
+func TestTryExpressionErrors(t *testing.T) {
+	tests := []string{
+		"try { f() }",
+		"try { f() } catch { g() }",
+		"try { f() } catch (1) { g() }",
+		"throw;",
+	}
+	for _, input := range tests {
+		l := lexer.New(input, "parser_test.go")
+		p := New(l)
+		p.ParseProgram()
+		if len(p.Errors) == 0 {
+			t.Errorf("expected parser errors for %q, got none", input)
+		}
+	}
+}
+
+func TestDeferStatement(t *testing.T) {
+	input := `fn(h) { defer close(h); if (ok) { defer log(1 + 2); } read(h) }`
+	l := lexer.New(input, "parser_test.go")
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+	function := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.FunctionLiteral)
+	stmt, ok := function.Body.Statements[0].(*ast.DeferStatement)
+	if !ok {
+		t.Fatalf("function.Body.Statements[0] not *ast.DeferStatement. got=%T", function.Body.Statements[0])
+	}
+	if !testIdentifier(t, stmt.Call.Function, "close") {
+		return
+	}
+	if stmt.String() != "defer close(h);" {
+		t.Errorf("stmt.String() wrong. got=%q", stmt.String())
+	}
+}
+
+func TestDeferStatementErrors(t *testing.T) {
+	tests := []string{
+		"defer close(h);",
+		"fn() { defer h; }",
+		"fn() { defer 1 + 2; }",
+		"fn() { defer close(h) }",
+	}
+	for _, input := range tests {
+		l := lexer.New(input, "parser_test.go")
+		p := New(l)
+		p.ParseProgram()
+		if len(p.Errors) == 0 {
+			t.Errorf("expected parser errors for %q, got none", input)
+		}
+	}
+}
+
+func TestStringLiteralExpression(t *testing.T) {
+	input := `"hello \"world\"";`
+	l := lexer.New(input, "parser_test.go")
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	literal, ok := stmt.Expression.(*ast.StringLiteral)
+	if !ok {
+		t.Fatalf("exp not *ast.StringLiteral. got=%T", stmt.Expression)
+	}
+	if literal.Value != `hello "world"` {
+		t.Errorf("literal.Value not %q. got=%q", `hello "world"`, literal.Value)
+	}
+	if literal.String() != `"hello \"world\""` {
+		t.Errorf("literal.String() wrong. got=%s", literal.String())
+	}
+}
+
+func TestImportExportStatements(t *testing.T) {
+	input := `import "lib/math.mk" as m;
+export let sqrt = fn(x) { m.sqrt(x) };
+export let [a, b] = pair;`
+	l := lexer.New(input, "parser_test.go")
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+	if len(program.Statements) != 3 {
+		t.Fatalf("program.Statements does not contain 3 statements. got=%d",
+			len(program.Statements))
+	}
+	imp, ok := program.Statements[0].(*ast.ImportStatement)
+	if !ok {
+		t.Fatalf("stmt not *ast.ImportStatement. got=%T", program.Statements[0])
+	}
+	if imp.Path.Value != "lib/math.mk" {
+		t.Errorf("imp.Path wrong. got=%s", imp.Path.Value)
+	}
+	if !testIdentifier(t, imp.Alias, "m") {
+		return
+	}
+	exp, ok := program.Statements[1].(*ast.ExportStatement)
+	if !ok {
+		t.Fatalf("stmt not *ast.ExportStatement. got=%T", program.Statements[1])
+	}
+	if !testLetStatement(t, exp.Statement, "sqrt") {
+		return
+	}
+	if program.Statements[0].String() != `import "lib/math.mk" as m;` {
+		t.Errorf("import String() wrong. got=%q", program.Statements[0].String())
+	}
+	if program.Statements[2].String() != `export let [a, b] = pair;` {
+		t.Errorf("export String() wrong. got=%q", program.Statements[2].String())
+	}
+}
+
+func TestImportExportErrors(t *testing.T) {
+	tests := []string{
+		`import lib as m;`,
+		`import "lib.mk";`,
+		`export fn() {};`,
+		`fn() { import "lib.mk" as m; }`,
+		`if (x) { export let a = 1; }`,
+	}
+	for _, input := range tests {
+		l := lexer.New(input, "parser_test.go")
+		p := New(l)
+		p.ParseProgram()
+		if len(p.Errors) == 0 {
+			t.Errorf("expected parser errors for %q, got none", input)
+		}
+	}
+}
+
+func TestMacroLiteralParsing(t *testing.T) {
+	input := `macro(x, y) { x + y; }`
+	l := lexer.New(input, "parser_test.go")
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	macro, ok := stmt.Expression.(*ast.MacroLiteral)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.MacroLiteral. got=%T", stmt.Expression)
+	}
+	if len(macro.Parameters) != 2 {
+		t.Fatalf("macro literal parameters wrong. want 2, got=%d\n", len(macro.Parameters))
+	}
+	testLiteralExpression(t, macro.Parameters[0], "x")
+	testLiteralExpression(t, macro.Parameters[1], "y")
+	if len(macro.Body.Statements) != 1 {
+		t.Fatalf("macro.Body.Statements has not 1 statements. got=%d\n", len(macro.Body.Statements))
+	}
+	bodyStmt, ok := macro.Body.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("macro body stmt is not ast.ExpressionStatement. got=%T", macro.Body.Statements[0])
+	}
+	testInfixExpression(t, bodyStmt.Expression, "x", "+", "y")
+
+	for _, input := range []string{"macro(x = 1) { x }", "macro(...xs) { xs }", "macro(x: int) { x }"} {
+		p := New(lexer.New(input, "parser_test.go"))
+		p.ParseProgram()
+		if len(p.Errors) == 0 {
+			t.Errorf("expected parser errors for %q, got none", input)
+		}
+	}
+}
+
+func TestRegisterOperators(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"a in b == c", "((a in b) == c)"},
+		{"a + b <> c <> d", "((a + b) <> (c <> d))"},
+		{"@a * b", "((@a) * b)"},
+		{"a ~ b", "(a ~ b)"},
+		{"~a ~ b", "((~a) ~ b)"},
+		{"a =~ b + c", "(a =~ (b + c))"},
+	}
+	for _, tt := range tests {
+		l := lexer.New(tt.input, "parser_test.go")
+		p := New(l)
+		registerTestOperators(t, p)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+		if actual := program.String(); actual != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, actual)
+		}
+	}
+}
+
+func registerTestOperators(t *testing.T, p *Parser) {
+	t.Helper()
+	if err := p.RegisterInfix("in", EQUALS, LEFT); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.RegisterInfix("<>", SUM-1, RIGHT); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.RegisterInfix("=~", EQUALS, LEFT); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.RegisterInfix("~", PRODUCT, LEFT); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.RegisterPrefix("@"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRegisterOperatorsKeepTokenTypes(t *testing.T) {
+	// operators named after token types must not take over the tokens of that type
+	tests := []struct {
+		operator string
+		input    string
+		expected string
+	}{
+		{"INT", "1 + 2 INT 3", "((1 + 2) INT 3)"},
+		{"IDENT", "a IDENT b", "(a IDENT b)"},
+		{"STRING", "\"a\" STRING \"b\"", "(\"a\" STRING \"b\")"},
+		{"LET", "let x = 1 LET 2;", "let x = (1 LET 2);"},
+		{"EOF", "a EOF b", "(a EOF b)"},
+	}
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input, "parser_test.go"))
+		if err := p.RegisterInfix(tt.operator, SUM-1, LEFT); err != nil {
+			t.Fatal(err)
+		}
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+		if actual := program.String(); actual != tt.expected {
+			t.Errorf("%s - expected=%q, got=%q", tt.operator, tt.expected, actual)
+		}
+	}
+}
+
+func TestRegisterOperatorErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		register func(p *Parser) error
+	}{
+		{"builtin infix", func(p *Parser) error { return p.RegisterInfix("+", SUM, LEFT) }},
+		{"builtin prefix", func(p *Parser) error { return p.RegisterPrefix("!") }},
+		{"keyword", func(p *Parser) error { return p.RegisterInfix("if", EQUALS, LEFT) }},
+		{"non operator token", func(p *Parser) error { return p.RegisterInfix("=", EQUALS, LEFT) }},
+		{"lowest precedence", func(p *Parser) error { return p.RegisterInfix("in", LOWEST, LEFT) }},
+		{"bad associativity", func(p *Parser) error { return p.RegisterInfix("in", SUM, 7) }},
+		{"mixed literal", func(p *Parser) error { return p.RegisterPrefix("@a") }},
+		{"twice", func(p *Parser) error {
+			if err := p.RegisterPrefix("@"); err != nil {
+				return nil
+			}
+			return p.RegisterPrefix("@")
+		}},
+	}
+	for _, tt := range tests {
+		p := New(lexer.New("", "parser_test.go"))
+		if err := tt.register(p); err == nil {
+			t.Errorf("%s - expected an error, got none", tt.name)
+		}
+	}
+}
+
+// TestStringRoundTrip re-parses the String() of every input in this file that parses,
+// checking it gives the same program back
+func TestTypeAnnotationParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x: int = 5;", "let x: int = 5;"},
+		{"let n: null = null;", "let n: null = null;"},
+		{"let f: fn(int, string) -> bool = g;", "let f: fn(int, string) -> bool = g;"},
+		{"let k: fn() -> fn(int) -> int = g;", "let k: fn() -> fn(int) -> int = g;"},
+		{"fn(a: int, b: int) -> int { a + b }", "fn(a: int, b: int) -> int { (a + b) }"},
+		{"fn(a, b: int = 1, ...rest) { a }", "fn(a, b: int = 1, ...rest) { a }"},
+		{"fn() -> null {}", "fn() -> null {  }"},
+		{"(a: int) -> int => a * 2", "(a: int) -> int => (a * 2)"},
+		{"map(xs, (x: string) => x)", "map(xs, (x: string) => x)"},
+	}
+	for _, tt := range tests {
+		l := lexer.New(tt.input, "parser_test.go")
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+		if program.String() != tt.expected {
+			t.Errorf("program.String() wrong. want=%q, got=%q", tt.expected, program.String())
+		}
+	}
+
+	l := lexer.New("let f = fn(a: int, b) -> string { b };", "parser_test.go")
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+	fn := program.Statements[0].(*ast.LetStatement).Value.(*ast.FunctionLiteral)
+	if len(fn.ParamTypes) != 2 || fn.ParamType(0).String() != "int" || fn.ParamType(1) != nil {
+		t.Errorf("fn.ParamTypes wrong. got=%v", fn.ParamTypes)
+	}
+	if nt, ok := fn.ReturnType.(*ast.NamedType); !ok || nt.Name != "string" {
+		t.Errorf("fn.ReturnType wrong. got=%#v", fn.ReturnType)
+	}
+}
+
+func TestTypeAnnotationErrors(t *testing.T) {
+	tests := []string{
+		"let x: = 5;",
+		"let x: 5 = 5;",
+		"let [a, b]: int = xs;",
+		"fn(a:) {}",
+		"fn() -> {}",
+		"let f: fn(int) = g;",
+		"fn(...rest: int) {}",
+	}
+	for _, input := range tests {
+		l := lexer.New(input, "parser_test.go")
+		p := New(l)
+		p.ParseProgram()
+		if len(p.Errors) == 0 {
+			t.Errorf("expected parser errors for %q, got none", input)
+		}
+	}
+}
+
+func TestStringRoundTrip(t *testing.T) {
+	f, err := goparser.ParseFile(gotoken.NewFileSet(), "parser_test.go", nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var inputs []string
+	goast.Inspect(f, func(n goast.Node) bool {
+		if lit, ok := n.(*goast.BasicLit); ok && lit.Kind == gotoken.STRING {
+			if s, err := strconv.Unquote(lit.Value); err == nil {
+				inputs = append(inputs, s)
+			}
+		}
+		return true
+	})
+
+	checked := 0
+	for _, input := range inputs {
+		p := New(lexer.New(input, "parser_test.go"))
+		program := p.ParseProgram()
+		if len(p.Errors) != 0 {
+			continue
+		}
+		checked++
+		p = New(lexer.New(program.String(), "parser_test.go"))
+		reparsed := p.ParseProgram()
+		if len(p.Errors) != 0 {
+			t.Errorf("String() of %q gave %q, which does not parse: %v", input, program.String(), p.Errors)
+			continue
+		}
+		if !ast.Equal(program, reparsed) {
+			t.Errorf("String() of %q gave %q, which parses to a different program %q",
+				input, program.String(), reparsed.String())
+		}
+	}
+	if checked == 0 {
+		t.Fatal("no inputs were checked")
+	}
+}
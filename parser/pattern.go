@@ -0,0 +1,297 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/geraldywy/monkey/ast"
+	"github.com/geraldywy/monkey/token"
+)
+
+func (p *Parser) parseMatchExpression(tkn *token.Token) (ast.Expression, error) {
+	exp := &ast.MatchExpression{Token: tkn}
+
+	if _, err := p.assertAndAdvanceTkn(token.LPAREN); err != nil {
+		return nil, err
+	}
+	nxtTkn, err := p.nextToken()
+	if err != nil {
+		return nil, err
+	}
+	if exp.Subject, err = p.parseExpression(nxtTkn, LOWEST); err != nil {
+		return nil, err
+	}
+	if _, err := p.assertAndAdvanceTkn(token.RPAREN); err != nil {
+		return nil, err
+	}
+	if _, err := p.assertAndAdvanceTkn(token.LBRACE); err != nil {
+		return nil, err
+	}
+
+	exp.Arms = make([]*ast.MatchArm, 0)
+	for p.assertPeek(token.RBRACE, token.EOF) != nil {
+		arm, err := p.parseMatchArm()
+		if err != nil {
+			return nil, err
+		}
+		exp.Arms = append(exp.Arms, arm)
+
+		// arms are comma separated, a trailing comma is allowed
+		if _, err := p.assertAndAdvanceTkn(token.COMMA); err != nil {
+			break
+		}
+	}
+
+	if _, err := p.assertAndAdvanceTkn(token.RBRACE); err != nil {
+		return nil, err
+	}
+
+	return exp, nil
+}
+
+func (p *Parser) parseMatchArm() (*ast.MatchArm, error) {
+	tkn, err := p.nextToken()
+	if err != nil {
+		return nil, err
+	}
+	arm := &ast.MatchArm{Token: tkn}
+
+	if arm.Pattern, err = p.parsePattern(tkn); err != nil {
+		return nil, err
+	}
+
+	// optional guard
+	if _, err := p.assertAndAdvanceTkn(token.IF); err == nil {
+		nxtTkn, err := p.nextToken()
+		if err != nil {
+			return nil, err
+		}
+		// a guard may hold a match with guards of its own, which must not end this one's
+		outer := p.noBareArrow
+		p.noBareArrow = true
+		arm.Guard, err = p.parseExpression(nxtTkn, LOWEST)
+		p.noBareArrow = outer
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := p.assertAndAdvanceTkn(token.ARROW); err != nil {
+		return nil, err
+	}
+
+	// the body is either a block, or a single expression
+	if arm.Body, err = p.parseConciseBody(); err != nil {
+		return nil, err
+	}
+
+	return arm, nil
+}
+
+func (p *Parser) parsePattern(tkn *token.Token) (ast.Pattern, error) {
+	switch tkn.Type {
+	case token.IDENT:
+		if tkn.Literal == "_" {
+			return &ast.WildcardPattern{Token: tkn}, nil
+		}
+		return &ast.IdentifierPattern{
+			Token: tkn,
+			Name:  &ast.Identifier{Token: tkn, Value: tkn.Literal},
+		}, nil
+	case token.INT:
+		val, err := p.parseIntegerLiteral(tkn)
+		if err != nil {
+			return nil, err
+		}
+		return &ast.LiteralPattern{Token: tkn, Value: val}, nil
+	case token.TRUE, token.FALSE:
+		val, err := p.parseBooleanLiteral(tkn)
+		if err != nil {
+			return nil, err
+		}
+		return &ast.LiteralPattern{Token: tkn, Value: val}, nil
+	case token.MINUS:
+		// only negative integer literals are allowed, arbitrary prefix expressions are not patterns
+		intTkn, err := p.assertAndAdvanceTkn(token.INT)
+		if err != nil {
+			return nil, err
+		}
+		val, err := p.parseIntegerLiteral(intTkn)
+		if err != nil {
+			return nil, err
+		}
+		return &ast.LiteralPattern{
+			Token: tkn,
+			Value: &ast.PrefixExpression{Token: tkn, Operator: tkn.Literal, Right: val},
+		}, nil
+	case token.LBRACKET:
+		return p.parseArrayPattern(tkn)
+	case token.LBRACE:
+		return p.parseHashPattern(tkn)
+	}
+
+	return nil, errors.New(fmt.Sprintf(
+		"%s line: %d col: %d unexpected token %s in pattern",
+		p.l.FileName,
+		p.l.LineNum,
+		p.l.LinePos,
+		tkn.Literal,
+	))
+}
+
+func (p *Parser) parseArrayPattern(tkn *token.Token) (*ast.ArrayPattern, error) {
+	pat := &ast.ArrayPattern{
+		Token:    tkn,
+		Elements: make([]ast.Pattern, 0),
+	}
+
+	for p.assertPeek(token.RBRACKET) != nil {
+		if _, err := p.assertAndAdvanceTkn(token.ELLIPSIS); err == nil {
+			nameTkn, err := p.assertAndAdvanceTkn(token.IDENT)
+			if err != nil {
+				return nil, err
+			}
+			pat.Rest = &ast.Identifier{Token: nameTkn, Value: nameTkn.Literal}
+
+			if p.assertPeek(token.RBRACKET) != nil {
+				return nil, errors.New(fmt.Sprintf(
+					"%s line: %d col: %d rest element must be the last element of an array pattern",
+					p.l.FileName,
+					p.l.LineNum,
+					p.l.LinePos,
+				))
+			}
+			break
+		}
+
+		nxtTkn, err := p.nextToken()
+		if err != nil {
+			return nil, err
+		}
+		elem, err := p.parsePattern(nxtTkn)
+		if err != nil {
+			return nil, err
+		}
+		pat.Elements = append(pat.Elements, elem)
+
+		// assert and skip the comma between elements
+		if _, err := p.assertAndAdvanceTkn(token.COMMA); err != nil {
+			break
+		}
+	}
+
+	if _, err := p.assertAndAdvanceTkn(token.RBRACKET); err != nil {
+		return nil, err
+	}
+
+	return pat, nil
+}
+
+func (p *Parser) parseHashPattern(tkn *token.Token) (*ast.HashPattern, error) {
+	pat := &ast.HashPattern{
+		Token: tkn,
+		Pairs: make([]*ast.HashPatternPair, 0),
+	}
+	seen := make(map[string]bool)
+
+	for p.assertPeek(token.RBRACE) != nil {
+		keyTkn, err := p.assertAndAdvanceTkn(token.IDENT)
+		if err != nil {
+			return nil, err
+		}
+		if seen[keyTkn.Literal] {
+			return nil, errors.New(fmt.Sprintf(
+				"%s line: %d col: %d duplicate key %s in hash pattern",
+				p.l.FileName,
+				p.l.LineNum,
+				p.l.LinePos,
+				keyTkn.Literal,
+			))
+		}
+		seen[keyTkn.Literal] = true
+		pair := &ast.HashPatternPair{
+			Key: &ast.Identifier{Token: keyTkn, Value: keyTkn.Literal},
+		}
+
+		if _, err := p.assertAndAdvanceTkn(token.COLON); err == nil {
+			nxtTkn, err := p.nextToken()
+			if err != nil {
+				return nil, err
+			}
+			if pair.Value, err = p.parsePattern(nxtTkn); err != nil {
+				return nil, err
+			}
+		} else {
+			// shorthand, {name} binds the value under key name to name
+			pair.Value = &ast.IdentifierPattern{Token: keyTkn, Name: pair.Key}
+			pair.Shorthand = true
+		}
+		pat.Pairs = append(pat.Pairs, pair)
+
+		// assert and skip the comma between pairs
+		if _, err := p.assertAndAdvanceTkn(token.COMMA); err != nil {
+			break
+		}
+	}
+
+	if _, err := p.assertAndAdvanceTkn(token.RBRACE); err != nil {
+		return nil, err
+	}
+
+	return pat, nil
+}
+
+// parseBindingPattern parses the destructuring target of a let statement. Unlike
+// match arms, literal patterns are rejected and each name may only be bound once.
+func (p *Parser) parseBindingPattern(tkn *token.Token) (ast.Pattern, error) {
+	pat, err := p.parsePattern(tkn)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.checkBindingPattern(pat); err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	for _, ident := range ast.Bindings(pat) {
+		if seen[ident.Value] {
+			return nil, errors.New(fmt.Sprintf(
+				"%s line: %d col: %d %s bound more than once in let pattern",
+				p.l.FileName,
+				p.l.LineNum,
+				p.l.LinePos,
+				ident.Value,
+			))
+		}
+		seen[ident.Value] = true
+	}
+
+	return pat, nil
+}
+
+func (p *Parser) checkBindingPattern(pat ast.Pattern) error {
+	switch pat := pat.(type) {
+	case *ast.LiteralPattern:
+		return errors.New(fmt.Sprintf(
+			"%s line: %d col: %d literal %s is not allowed in let pattern",
+			p.l.FileName,
+			p.l.LineNum,
+			p.l.LinePos,
+			pat.String(),
+		))
+	case *ast.ArrayPattern:
+		for _, e := range pat.Elements {
+			if err := p.checkBindingPattern(e); err != nil {
+				return err
+			}
+		}
+	case *ast.HashPattern:
+		for _, pair := range pat.Pairs {
+			if err := p.checkBindingPattern(pair.Value); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
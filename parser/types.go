@@ -0,0 +1,43 @@
+package parser
+
+import (
+	"github.com/geraldywy/monkey/ast"
+	"github.com/geraldywy/monkey/token"
+)
+
+// parseType parses the type annotation following a ':' or '->', e.g. int or fn(int) -> bool
+func (p *Parser) parseType() (ast.TypeExpr, error) {
+	tkn, err := p.assertAndAdvanceTkn(token.IDENT, token.NULL, token.FUNCTION)
+	if err != nil {
+		return nil, err
+	}
+	if tkn.Type != token.FUNCTION {
+		return &ast.NamedType{Token: tkn, Name: tkn.Literal}, nil
+	}
+
+	ft := &ast.FunctionType{Token: tkn, Parameters: []ast.TypeExpr{}}
+	if _, err := p.assertAndAdvanceTkn(token.LPAREN); err != nil {
+		return nil, err
+	}
+	for p.assertPeek(token.RPAREN) != nil {
+		param, err := p.parseType()
+		if err != nil {
+			return nil, err
+		}
+		ft.Parameters = append(ft.Parameters, param)
+		if _, err := p.assertAndAdvanceTkn(token.COMMA); err != nil {
+			break
+		}
+	}
+	if _, err := p.assertAndAdvanceTkn(token.RPAREN); err != nil {
+		return nil, err
+	}
+	if _, err := p.assertAndAdvanceTkn(token.THINARROW); err != nil {
+		return nil, err
+	}
+	if ft.Return, err = p.parseType(); err != nil {
+		return nil, err
+	}
+
+	return ft, nil
+}
@@ -4,16 +4,24 @@ import (
 	"bufio"
 	"fmt"
 	"io"
+	"strings"
 
+	"github.com/geraldywy/monkey/ast"
 	"github.com/geraldywy/monkey/parser"
+	"github.com/geraldywy/monkey/types"
 
 	"github.com/geraldywy/monkey/lexer"
 )
 
 const PROMPT = ">> "
 
+// TYPE_COMMAND prefixes input whose inferred type is printed instead, e.g. :type fn(x) { x }
+const TYPE_COMMAND = ":type "
+
 func Start(in io.Reader, out io.Writer, filename string) {
 	scanner := bufio.NewScanner(in)
+	// the lets entered so far, which typed input may refer to
+	var defs []ast.Statement
 	for {
 		fmt.Printf(PROMPT)
 		scanned := scanner.Scan()
@@ -21,6 +29,10 @@ func Start(in io.Reader, out io.Writer, filename string) {
 			return
 		}
 		line := scanner.Text()
+		if strings.HasPrefix(line, TYPE_COMMAND) {
+			printTypes(out, defs, strings.TrimPrefix(line, TYPE_COMMAND), filename)
+			continue
+		}
 		l := lexer.New(line, filename)
 		p := parser.New(l)
 		program := p.ParseProgram()
@@ -30,6 +42,63 @@ func Start(in io.Reader, out io.Writer, filename string) {
 		}
 		io.WriteString(out, program.String())
 		io.WriteString(out, "\n")
+		for _, s := range program.Statements {
+			switch s.(type) {
+			case *ast.LetStatement, *ast.ExportStatement:
+				// an ill-typed let would make every later typed input fail too
+				if infers(defs, s, filename) {
+					defs = append(defs, s)
+				}
+			}
+		}
+	}
+}
+
+// infers reports whether stmt is well typed following defs
+func infers(defs []ast.Statement, stmt ast.Statement, filename string) bool {
+	program := &ast.Program{Statements: append(append([]ast.Statement{}, defs...), stmt)}
+	_, errs := types.Infer(program, filename)
+	return len(errs) == 0
+}
+
+// printTypes prints the type of each statement of input, the type of an expression or the
+// names a let declares
+func printTypes(out io.Writer, defs []ast.Statement, input, filename string) {
+	p := parser.New(lexer.New(input, filename))
+	program := p.ParseProgram()
+	if len(p.Errors) != 0 {
+		printParserErrors(out, p.Errors)
+		return
+	}
+	stmts := program.Statements
+	program.Statements = append(append([]ast.Statement{}, defs...), stmts...)
+	inf, errs := types.Infer(program, filename)
+	if len(errs) != 0 {
+		io.WriteString(out, " type errors:\n")
+		for _, err := range errs {
+			io.WriteString(out, "\t"+err.Error()+"\n")
+		}
+		return
+	}
+
+	for _, s := range stmts {
+		if es, ok := s.(*ast.ExportStatement); ok {
+			s = es.Statement
+		}
+		switch s := s.(type) {
+		case *ast.ExpressionStatement:
+			io.WriteString(out, inf.TypeOf(s.Expression).String()+"\n")
+		case *ast.LetStatement:
+			idents := ast.Bindings(s.Pattern)
+			if s.Name != nil {
+				idents = []*ast.Identifier{s.Name}
+			}
+			for _, ident := range idents {
+				if scheme, ok := inf.Defs[inf.Symbols.Defs[ident]]; ok {
+					io.WriteString(out, ident.Value+": "+scheme.String()+"\n")
+				}
+			}
+		}
 	}
 }
 
@@ -0,0 +1,48 @@
+package repl_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/geraldywy/monkey/repl"
+)
+
+func TestTypeCommand(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string // lines the output must contain
+		excluded []string // lines it must not
+	}{
+		{"let id = fn(x) { x };\n:type id(1)\n", []string{"int"}, nil},
+		{"let twice = fn(f, x) { f(f(x)) };\n:type twice\n", []string{"fn(fn('a) -> 'a, 'a) -> 'a"}, nil},
+		{"let bad = 5 + true;\nlet x = 1;\n:type x\n", []string{"int"}, []string{" type errors:"}},
+		{":type let s = \"a\";\n", []string{"s: string"}, nil},
+		{":type 1 + true\n", []string{" type errors:"}, nil},
+	}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		repl.Start(strings.NewReader(tt.input), &out, "repl_test.go")
+		lines := strings.Split(out.String(), "\n")
+		for _, want := range tt.expected {
+			if !contains(lines, want) {
+				t.Errorf("%q - output is missing %q. got=%q", tt.input, want, out.String())
+			}
+		}
+		for _, unwanted := range tt.excluded {
+			if contains(lines, unwanted) {
+				t.Errorf("%q - output has unexpected %q. got=%q", tt.input, unwanted, out.String())
+			}
+		}
+	}
+}
+
+func contains(lines []string, line string) bool {
+	for _, l := range lines {
+		if l == line {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,366 @@
+// Package resolver links every identifier in a Monkey program to the declaration it refers
+// to, reporting the names that refer to nothing before the program is run.
+package resolver
+
+import (
+	"fmt"
+
+	"github.com/geraldywy/monkey/ast"
+)
+
+type Kind int
+
+const (
+	Predeclared Kind = iota // a builtin, it has no declaration
+	Let
+	Param
+	Import
+	Match // bound by the pattern of a match arm
+	Catch
+)
+
+var kindNames = [...]string{"predeclared", "let", "param", "import", "match", "catch"}
+
+func (k Kind) String() string { return kindNames[k] }
+
+// Universe lists the names declared in every program, the macro builtins
+var Universe = []string{"quote", "unquote"}
+
+// Symbol is a declared name
+type Symbol struct {
+	Name     string
+	Kind     Kind
+	Decl     *ast.Identifier // nil for predeclared names
+	Value    ast.Expression  // the value a let binds to the name alone, nil otherwise
+	Exported bool
+	Scope    *Scope
+	Uses     []*ast.Identifier // in the order they are resolved
+	// Shadows is the declaration of the same name in an enclosing scope that was visible
+	// when this one was made, if any
+	Shadows *Symbol
+}
+
+// Scope holds the names declared by a program, block, function, match arm or catch clause
+type Scope struct {
+	Parent   *Scope
+	Node     ast.Node  // the node that opens the scope, nil for the universe
+	Symbols  []*Symbol // in declaration order, including redeclared ones
+	Children []*Scope
+
+	names   map[string]*Symbol
+	pending map[string]*ast.Identifier // names declared later in the scope
+	// function bodies are resolved once the scope they are declared in is complete, as they
+	// may refer to names declared after them, e.g. for mutual recursion
+	deferred []func()
+}
+
+// Lookup returns the last declaration of name in s or the scopes enclosing it
+func (s *Scope) Lookup(name string) *Symbol {
+	for ; s != nil; s = s.Parent {
+		if sym, ok := s.names[name]; ok {
+			return sym
+		}
+	}
+
+	return nil
+}
+
+// Info is the symbol table of a program
+type Info struct {
+	Universe *Scope
+	Scopes   map[ast.Node]*Scope
+	Defs     map[*ast.Identifier]*Symbol
+	Uses     map[*ast.Identifier]*Symbol
+}
+
+// SymbolOf returns the symbol ident declares or refers to
+func (info *Info) SymbolOf(ident *ast.Identifier) *Symbol {
+	if sym, ok := info.Defs[ident]; ok {
+		return sym
+	}
+
+	return info.Uses[ident]
+}
+
+// Error is an identifier that does not refer to a declaration
+type Error struct {
+	FileName string
+	Ident    *ast.Identifier
+	Decl     *ast.Identifier // the declaration that comes after the use, if any
+}
+
+func (e *Error) Error() string {
+	tkn := e.Ident.Token
+	if e.Decl != nil {
+		return fmt.Sprintf("%s line: %d col: %d %s used before its definition at line %d col %d",
+			e.FileName, tkn.Line, tkn.Column, e.Ident.Value, e.Decl.Token.Line, e.Decl.Token.Column)
+	}
+
+	return fmt.Sprintf("%s line: %d col: %d undefined: %s", e.FileName, tkn.Line, tkn.Column, e.Ident.Value)
+}
+
+// Resolve builds the symbol table of program. Names are visible from the end of their
+// declaration to the end of the enclosing scope, a block, function body, match arm or catch
+// clause, except within function bodies, which see every name of the scopes they are
+// declared in. predeclared names are added to the Universe. fileName is only used in
+// errors.
+func Resolve(program *ast.Program, fileName string, predeclared ...string) (*Info, []error) {
+	r := &resolver{
+		fileName: fileName,
+		info: &Info{
+			Scopes: make(map[ast.Node]*Scope),
+			Defs:   make(map[*ast.Identifier]*Symbol),
+			Uses:   make(map[*ast.Identifier]*Symbol),
+		},
+	}
+	r.openScope(nil, nil)
+	r.info.Universe = r.scope
+	for _, names := range [][]string{Universe, predeclared} {
+		for _, name := range names {
+			sym := &Symbol{Name: name, Kind: Predeclared, Scope: r.scope}
+			r.scope.names[name] = sym
+			r.scope.Symbols = append(r.scope.Symbols, sym)
+		}
+	}
+
+	r.openScope(program, program.Statements)
+	r.statements(program.Statements)
+	r.closeScope()
+	r.closeScope()
+
+	return r.info, r.errors
+}
+
+type resolver struct {
+	fileName string
+	info     *Info
+	scope    *Scope
+	errors   []error
+}
+
+func (r *resolver) openScope(node ast.Node, stmts []ast.Statement) {
+	s := &Scope{
+		Parent:  r.scope,
+		Node:    node,
+		names:   make(map[string]*Symbol),
+		pending: make(map[string]*ast.Identifier),
+	}
+	if r.scope != nil {
+		r.scope.Children = append(r.scope.Children, s)
+	}
+	if node != nil {
+		r.info.Scopes[node] = s
+	}
+	for _, stmt := range stmts {
+		for _, ident := range declaredBy(stmt) {
+			if _, ok := s.pending[ident.Value]; !ok && ident.Value != "_" {
+				s.pending[ident.Value] = ident
+			}
+		}
+	}
+	r.scope = s
+}
+
+func (r *resolver) closeScope() {
+	s := r.scope
+	for len(s.deferred) != 0 {
+		d := s.deferred[0]
+		s.deferred = s.deferred[1:]
+		d()
+	}
+	r.scope = s.Parent
+}
+
+// declaredBy returns the names a statement declares in the scope it appears in
+func declaredBy(stmt ast.Statement) []*ast.Identifier {
+	switch stmt := stmt.(type) {
+	case *ast.LetStatement:
+		if stmt.Pattern != nil {
+			return ast.Bindings(stmt.Pattern)
+		}
+		return []*ast.Identifier{stmt.Name}
+	case *ast.ExportStatement:
+		return declaredBy(stmt.Statement)
+	case *ast.ImportStatement:
+		return []*ast.Identifier{stmt.Alias}
+	}
+
+	return nil
+}
+
+func (r *resolver) declare(ident *ast.Identifier, kind Kind) *Symbol {
+	sym := &Symbol{Name: ident.Value, Kind: kind, Decl: ident, Scope: r.scope}
+	r.info.Defs[ident] = sym
+	// _ discards the value, it is never visible
+	if ident.Value == "_" {
+		return sym
+	}
+	if _, redeclared := r.scope.names[ident.Value]; !redeclared {
+		sym.Shadows = r.scope.Parent.Lookup(ident.Value)
+	}
+	r.scope.names[ident.Value] = sym
+	r.scope.Symbols = append(r.scope.Symbols, sym)
+	delete(r.scope.pending, ident.Value)
+
+	return sym
+}
+
+func (r *resolver) use(ident *ast.Identifier) {
+	for s := r.scope; s != nil; s = s.Parent {
+		if sym, ok := s.names[ident.Value]; ok {
+			sym.Uses = append(sym.Uses, ident)
+			r.info.Uses[ident] = sym
+			return
+		}
+		if decl, ok := s.pending[ident.Value]; ok {
+			r.errors = append(r.errors, &Error{FileName: r.fileName, Ident: ident, Decl: decl})
+			return
+		}
+	}
+	r.errors = append(r.errors, &Error{FileName: r.fileName, Ident: ident})
+}
+
+func (r *resolver) statements(stmts []ast.Statement) {
+	for _, s := range stmts {
+		r.statement(s)
+	}
+}
+
+func (r *resolver) statement(s ast.Statement) {
+	switch s := s.(type) {
+	case *ast.LetStatement:
+		r.let(s)
+	case *ast.ExportStatement:
+		for _, sym := range r.let(s.Statement) {
+			sym.Exported = true
+		}
+	case *ast.ReturnStatement:
+		r.expr(s.ReturnValue)
+	case *ast.ThrowStatement:
+		r.expr(s.Value)
+	case *ast.DeferStatement:
+		r.expr(s.Call)
+	case *ast.ImportStatement:
+		r.declare(s.Alias, Import)
+	case *ast.ExpressionStatement:
+		r.expr(s.Expression)
+	case *ast.BlockStatement:
+		r.block(s)
+	}
+}
+
+func (r *resolver) let(ls *ast.LetStatement) []*Symbol {
+	if ls.Pattern != nil {
+		r.expr(ls.Value)
+		syms := []*Symbol{}
+		for _, ident := range ast.Bindings(ls.Pattern) {
+			syms = append(syms, r.declare(ident, Let))
+		}
+		return syms
+	}
+
+	// a function may call itself, so it is declared before its body is resolved
+	if _, ok := ls.Value.(*ast.FunctionLiteral); ok {
+		sym := r.declare(ls.Name, Let)
+		sym.Value = ls.Value
+		r.expr(ls.Value)
+		return []*Symbol{sym}
+	}
+	r.expr(ls.Value)
+	sym := r.declare(ls.Name, Let)
+	sym.Value = ls.Value
+
+	return []*Symbol{sym}
+}
+
+func (r *resolver) block(b *ast.BlockStatement) {
+	if b == nil {
+		return
+	}
+	r.openScope(b, b.Statements)
+	r.statements(b.Statements)
+	r.closeScope()
+}
+
+func (r *resolver) expr(e ast.Expression) {
+	switch e := e.(type) {
+	case *ast.Identifier:
+		r.use(e)
+	case *ast.PrefixExpression:
+		r.expr(e.Right)
+	case *ast.InfixExpression:
+		r.expr(e.Left)
+		r.expr(e.Right)
+	case *ast.IfExpression:
+		r.expr(e.Condition)
+		r.block(e.Consequence)
+		r.block(e.Alternative)
+	case *ast.FunctionLiteral:
+		r.deferred(func() { r.function(e, e.Parameters, e.Defaults, e.Rest, e.Body) })
+	case *ast.MacroLiteral:
+		r.deferred(func() { r.function(e, e.Parameters, nil, nil, e.Body) })
+	case *ast.CallExpression:
+		r.expr(e.Function)
+		for _, arg := range e.Arguments {
+			r.expr(arg)
+		}
+	case *ast.SpreadExpression:
+		r.expr(e.Value)
+	case *ast.NamedArgument:
+		// the name refers to a parameter of the function called, not to a declaration
+		r.expr(e.Value)
+	case *ast.MemberExpression:
+		// the property is looked up in the object at runtime
+		r.expr(e.Object)
+	case *ast.IndexExpression:
+		r.expr(e.Left)
+		r.expr(e.Index)
+	case *ast.MatchExpression:
+		r.expr(e.Subject)
+		for _, arm := range e.Arms {
+			r.openScope(arm, nil)
+			for _, ident := range ast.Bindings(arm.Pattern) {
+				r.declare(ident, Match)
+			}
+			r.expr(arm.Guard)
+			r.block(arm.Body)
+			r.closeScope()
+		}
+	case *ast.TryExpression:
+		r.block(e.Block)
+		if e.Catch != nil {
+			r.openScope(e, nil)
+			r.declare(e.CatchParam, Catch)
+			r.block(e.Catch)
+			r.closeScope()
+		}
+		r.block(e.Finally)
+	}
+}
+
+// deferred runs resolve once the current scope is complete, within that scope
+func (r *resolver) deferred(resolve func()) {
+	outer := r.scope
+	outer.deferred = append(outer.deferred, func() {
+		saved := r.scope
+		r.scope = outer
+		resolve()
+		r.scope = saved
+	})
+}
+
+func (r *resolver) function(node ast.Node, params []*ast.Identifier, defaults []ast.Expression, rest *ast.Identifier, body *ast.BlockStatement) {
+	r.openScope(node, nil)
+	for i, p := range params {
+		// a default may refer to the parameters before it
+		if i < len(defaults) {
+			r.expr(defaults[i])
+		}
+		r.declare(p, Param)
+	}
+	if rest != nil {
+		r.declare(rest, Param)
+	}
+	r.block(body)
+	r.closeScope()
+}
@@ -0,0 +1,109 @@
+package resolver_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/geraldywy/monkey/ast"
+	"github.com/geraldywy/monkey/lexer"
+	"github.com/geraldywy/monkey/parser"
+	"github.com/geraldywy/monkey/resolver"
+)
+
+func parse(t *testing.T, input string) *ast.Program {
+	t.Helper()
+	p := parser.New(lexer.New(input, "resolver_test.go"))
+	program := p.ParseProgram()
+	if len(p.Errors) != 0 {
+		t.Fatalf("parser errors for %q: %v", input, p.Errors)
+	}
+
+	return program
+}
+
+func TestResolveErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"let x = 1; x;", []string{}},
+		{"y;", []string{"resolver_test.go line: 1 col: 1 undefined: y"}},
+		{"let x = y; let y = 1;", []string{"resolver_test.go line: 1 col: 9 y used before its definition at line 1 col 16"}},
+		{"let x = x;", []string{"resolver_test.go line: 1 col: 9 x used before its definition at line 1 col 5"}},
+		{"let x = 1; let x = x + 1;", []string{}},
+		{"if (true) { let x = 1; } x;", []string{"resolver_test.go line: 1 col: 26 undefined: x"}},
+		{"let f = fn() { g() }; let g = fn() { f() };", []string{}},
+		{"let f = fn(n) { f(n) };", []string{}},
+		{"fn(a, b = a, c = d) { a };", []string{"resolver_test.go line: 1 col: 18 undefined: d"}},
+		{"fn(...rest) { rest };", []string{}},
+		{"f(x: 1);", []string{"resolver_test.go line: 1 col: 1 undefined: f"}},
+		{"let m = 1; m.x;", []string{}},
+		{"match (1) { [a, ...b] if a => b, _ => a };", []string{"resolver_test.go line: 1 col: 39 undefined: a"}},
+		{"try { throw 1; } catch (e) { e } finally { e };", []string{"resolver_test.go line: 1 col: 44 undefined: e"}},
+		{`import "m" as m; m.f();`, []string{}},
+		{"let [a, { b }] = xs;", []string{"resolver_test.go line: 1 col: 18 undefined: xs"}},
+		{"let _ = 1; _;", []string{"resolver_test.go line: 1 col: 12 undefined: _"}},
+		{"macro(a) { quote(unquote(a)) };", []string{}},
+	}
+	for _, tt := range tests {
+		_, errs := resolver.Resolve(parse(t, tt.input), "resolver_test.go")
+		got := []string{}
+		for _, err := range errs {
+			got = append(got, err.Error())
+		}
+		if fmt.Sprint(got) != fmt.Sprint(tt.expected) {
+			t.Errorf("%q - errors wrong.\nwant=%q\ngot=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestResolvePredeclared(t *testing.T) {
+	_, errs := resolver.Resolve(parse(t, "puts(len(xs));"), "resolver_test.go", "puts", "len")
+	if len(errs) != 1 || errs[0].Error() != "resolver_test.go line: 1 col: 10 undefined: xs" {
+		t.Errorf("wrong errors, got=%v", errs)
+	}
+}
+
+func TestResolveSymbols(t *testing.T) {
+	input := "let x = 1; export let f = fn(x) { x }; f(x);"
+	program := parse(t, input)
+	info, errs := resolver.Resolve(program, "resolver_test.go")
+	if len(errs) != 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+
+	// every use is linked to the position of its declaration
+	links := []string{}
+	ast.Inspect(program, func(node ast.Node) bool {
+		if ident, ok := node.(*ast.Identifier); ok {
+			if sym := info.Uses[ident]; sym != nil {
+				links = append(links, fmt.Sprintf("%d->%d", ident.Token.Column, sym.Decl.Token.Column))
+			}
+		}
+		return true
+	})
+	if want := "[35->30 40->23 42->5]"; fmt.Sprint(links) != want {
+		t.Errorf("uses linked wrong. want=%s, got=%v", want, links)
+	}
+
+	global := info.Scopes[program]
+	if global == nil || global.Parent != info.Universe {
+		t.Fatalf("program scope wrong, got=%+v", global)
+	}
+	x, f := global.Lookup("x"), global.Lookup("f")
+	if x.Kind != resolver.Let || len(x.Uses) != 1 || x.Exported {
+		t.Errorf("x wrong, got=%+v", x)
+	}
+	if f.Kind != resolver.Let || !f.Exported || f.Value == nil {
+		t.Errorf("f wrong, got=%+v", f)
+	}
+
+	fn := f.Value.(*ast.FunctionLiteral)
+	param := info.SymbolOf(fn.Parameters[0])
+	if param.Kind != resolver.Param || param.Scope != info.Scopes[fn] || param.Shadows != x {
+		t.Errorf("param wrong, got=%+v", param)
+	}
+	if info.Scopes[fn.Body].Parent != info.Scopes[fn] {
+		t.Errorf("function body should be resolved within the parameters scope")
+	}
+}
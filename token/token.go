@@ -1,6 +1,8 @@
 package token
 
 import (
+	"sort"
+
 	"github.com/geraldywy/monkey/utils"
 )
 
@@ -9,15 +11,21 @@ type TokenType string
 type Token struct {
 	Type    TokenType
 	Literal string
+
+	// position of the token's first character, both are 1-indexed
+	Line   int
+	Column int
 }
 
 const (
 	ILLEGAL = "ILLEGAL"
 	EOF     = "EOF"
+	COMMENT = "COMMENT" // // to the end of the line, only emitted when the lexer scans comments
 
 	// Identifiers + literals
-	IDENT = "IDENT" // add, foobar, x, y, ...
-	INT   = "INT"   // 1343456
+	IDENT  = "IDENT"  // add, foobar, x, y, ...
+	INT    = "INT"    // 1343456
+	STRING = "STRING" // "foobar"
 
 	// Operators
 	ASSIGN     = "="
@@ -26,6 +34,14 @@ const (
 	BANG       = "!"
 	ASTERISK   = "*"
 	SLASH      = "/"
+	PERCENT    = "%"
+	POWER      = "**"
+	AMPERSAND  = "&"
+	PIPE       = "|"
+	CARET      = "^"
+	TILDE      = "~"
+	LSHIFT     = "<<"
+	RSHIFT     = ">>"
 	LT         = "<"
 	GT         = ">"
 	EQ         = "=="
@@ -34,6 +50,12 @@ const (
 	LTE        = "<="
 	PLUSPLUS   = "++"
 	MINUSMINUS = "--"
+	ARROW      = "=>"
+	THINARROW  = "->" // precedes a return type, e.g. fn(a: int) -> int
+	ELLIPSIS   = "..."
+	NULLISH    = "??"
+	OPTDOT     = "?."
+	OPTLBRACK  = "?["
 
 	// Delimiters
 	COMMA     = ","
@@ -42,6 +64,10 @@ const (
 	RPAREN    = ")"
 	LBRACE    = "{"
 	RBRACE    = "}"
+	LBRACKET  = "["
+	RBRACKET  = "]"
+	COLON     = ":"
+	DOT       = "."
 
 	// Keywords
 	FUNCTION = "FUNCTION"
@@ -51,6 +77,17 @@ const (
 	FALSE    = "FALSE"
 	IF       = "IF"
 	ELSE     = "ELSE"
+	MATCH    = "MATCH"
+	NULL     = "NULL"
+	TRY      = "TRY"
+	CATCH    = "CATCH"
+	FINALLY  = "FINALLY"
+	THROW    = "THROW"
+	DEFER    = "DEFER"
+	IMPORT   = "IMPORT"
+	EXPORT   = "EXPORT"
+	AS       = "AS"
+	MACRO    = "MACRO"
 )
 
 var SingleToken = map[byte]TokenType{
@@ -68,6 +105,15 @@ var SingleToken = map[byte]TokenType{
 	'/': SLASH,
 	'<': LT,
 	'>': GT,
+	'[': LBRACKET,
+	']': RBRACKET,
+	':': COLON,
+	'.': DOT,
+	'%': PERCENT,
+	'&': AMPERSAND,
+	'|': PIPE,
+	'^': CARET,
+	'~': TILDE,
 }
 
 var DoubleToken = map[string]TokenType{
@@ -77,16 +123,50 @@ var DoubleToken = map[string]TokenType{
 	"<=": LTE,
 	"++": PLUSPLUS,
 	"--": MINUSMINUS,
+	"=>": ARROW,
+	"->": THINARROW,
+	"??": NULLISH,
+	"?.": OPTDOT,
+	"?[": OPTLBRACK,
+	"**": POWER,
+	"<<": LSHIFT,
+	">>": RSHIFT,
+}
+
+var TripleToken = map[string]TokenType{
+	"...": ELLIPSIS,
 }
 
 var reservedKeywords = map[string]TokenType{
-	"fn":     FUNCTION,
-	"let":    LET,
-	"if":     IF,
-	"else":   ELSE,
-	"return": RETURN,
-	"true":   TRUE,
-	"false":  FALSE,
+	"fn":      FUNCTION,
+	"let":     LET,
+	"if":      IF,
+	"else":    ELSE,
+	"return":  RETURN,
+	"true":    TRUE,
+	"false":   FALSE,
+	"match":   MATCH,
+	"null":    NULL,
+	"try":     TRY,
+	"catch":   CATCH,
+	"finally": FINALLY,
+	"throw":   THROW,
+	"defer":   DEFER,
+	"import":  IMPORT,
+	"export":  EXPORT,
+	"as":      AS,
+	"macro":   MACRO,
+}
+
+// Keywords returns the reserved keywords in alphabetical order
+func Keywords() []string {
+	keywords := make([]string, 0, len(reservedKeywords))
+	for k := range reservedKeywords {
+		keywords = append(keywords, k)
+	}
+	sort.Strings(keywords)
+
+	return keywords
 }
 
 func LookupTType(literal string) TokenType {
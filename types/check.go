@@ -0,0 +1,497 @@
+package types
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/geraldywy/monkey/ast"
+	"github.com/geraldywy/monkey/resolver"
+	"github.com/geraldywy/monkey/token"
+)
+
+// Error is a type error
+type Error struct {
+	FileName string
+	Line     int
+	Column   int
+	Msg      string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s line: %d col: %d %s", e.FileName, e.Line, e.Column, e.Msg)
+}
+
+// Info holds the types found by Check
+type Info struct {
+	Symbols *resolver.Info
+	Types   map[ast.Expression]Type // the type of every expression checked
+	Defs    map[*resolver.Symbol]Type
+}
+
+// TypeOf returns the type of e, Any if it was not checked
+func (info *Info) TypeOf(e ast.Expression) Type {
+	if t, ok := info.Types[e]; ok {
+		return t
+	}
+
+	return Any
+}
+
+// Check type checks program, returning the errors found ordered by position, including the
+// names that do not resolve. Unannotated lets take the type of their value, while
+// unannotated parameters and return types are Any, so unannotated code is only checked
+// where the types of literals conflict, e.g. 5 + true. predeclared names are typed Any.
+func Check(program *ast.Program, fileName string, predeclared ...string) (*Info, []error) {
+	symbols, errs := resolver.Resolve(program, fileName, predeclared...)
+	c := &checker{
+		fileName: fileName,
+		info: &Info{
+			Symbols: symbols,
+			Types:   make(map[ast.Expression]Type),
+			Defs:    make(map[*resolver.Symbol]Type),
+		},
+		annotations: make(map[ast.TypeExpr]Type),
+		signatures:  make(map[*ast.FunctionLiteral]*Func),
+		declared:    make(map[*ast.Identifier]Type),
+		errors:      errs,
+	}
+
+	// the declared types of lets are known up front, as a function body may refer to names
+	// declared after it
+	ast.Inspect(program, func(node ast.Node) bool {
+		if ls, ok := node.(*ast.LetStatement); ok && ls.Name != nil {
+			if ls.Type != nil {
+				c.declared[ls.Name] = c.annotation(ls.Type)
+			} else if fn, ok := ls.Value.(*ast.FunctionLiteral); ok {
+				c.declared[ls.Name] = c.signature(fn)
+			}
+		}
+		return true
+	})
+	for _, s := range program.Statements {
+		c.statement(s)
+	}
+
+	sort.SliceStable(c.errors, func(i, j int) bool {
+		li, ci := position(c.errors[i])
+		lj, cj := position(c.errors[j])
+		if li != lj {
+			return li < lj
+		}
+		return ci < cj
+	})
+
+	return c.info, c.errors
+}
+
+func position(err error) (int, int) {
+	switch err := err.(type) {
+	case *Error:
+		return err.Line, err.Column
+	case *resolver.Error:
+		return err.Ident.Token.Line, err.Ident.Token.Column
+	}
+
+	return 0, 0
+}
+
+type checker struct {
+	fileName    string
+	info        *Info
+	annotations map[ast.TypeExpr]Type
+	signatures  map[*ast.FunctionLiteral]*Func
+	declared    map[*ast.Identifier]Type // the annotated types of lets, before they are checked
+	returns     []Type                   // the return types of the enclosing functions, innermost last
+	errors      []error
+}
+
+func (c *checker) errorf(tkn *token.Token, format string, args ...interface{}) {
+	c.errors = append(c.errors, &Error{
+		FileName: c.fileName,
+		Line:     tkn.Line,
+		Column:   tkn.Column,
+		Msg:      fmt.Sprintf(format, args...),
+	})
+}
+
+// annotation returns the type an annotation refers to, Any for a missing one
+func (c *checker) annotation(te ast.TypeExpr) Type {
+	if te == nil {
+		return Any
+	}
+	if t, ok := c.annotations[te]; ok {
+		return t
+	}
+
+	var t Type = Any
+	switch te := te.(type) {
+	case *ast.NamedType:
+		if b, ok := basics[te.Name]; ok {
+			t = b
+		} else {
+			c.errorf(te.Token, "unknown type %s", te.Name)
+		}
+	case *ast.FunctionType:
+		ft := &Func{Params: []Type{}}
+		for _, p := range te.Parameters {
+			ft.Params = append(ft.Params, c.annotation(p))
+		}
+		ft.Return = c.annotation(te.Return)
+		t = ft
+	}
+	c.annotations[te] = t
+
+	return t
+}
+
+// signature returns the type of fn as annotated
+func (c *checker) signature(fn *ast.FunctionLiteral) *Func {
+	if sig, ok := c.signatures[fn]; ok {
+		return sig
+	}
+
+	sig := &Func{Params: []Type{}, Variadic: fn.Rest != nil}
+	for i := range fn.Parameters {
+		sig.Params = append(sig.Params, c.annotation(fn.ParamType(i)))
+	}
+	sig.Return = c.annotation(fn.ReturnType)
+	c.signatures[fn] = sig
+
+	return sig
+}
+
+func (c *checker) symbolType(sym *resolver.Symbol) Type {
+	if sym == nil {
+		return Any
+	}
+	if t, ok := c.info.Defs[sym]; ok {
+		return t
+	}
+	if t, ok := c.declared[sym.Decl]; ok {
+		return t
+	}
+
+	return Any
+}
+
+func (c *checker) statement(s ast.Statement) {
+	switch s := s.(type) {
+	case *ast.LetStatement:
+		c.let(s)
+	case *ast.ExportStatement:
+		c.let(s.Statement)
+	case *ast.ReturnStatement:
+		t := c.expr(s.ReturnValue)
+		if len(c.returns) != 0 {
+			c.assign(s.ReturnValue, t, c.returns[len(c.returns)-1], "return statement")
+		}
+	case *ast.ThrowStatement:
+		c.expr(s.Value)
+	case *ast.DeferStatement:
+		c.expr(s.Call)
+	case *ast.ExpressionStatement:
+		c.expr(s.Expression)
+	case *ast.BlockStatement:
+		c.block(s)
+	}
+}
+
+func (c *checker) let(ls *ast.LetStatement) {
+	t := c.expr(ls.Value)
+	if ls.Name == nil {
+		// destructured names are not checked
+		return
+	}
+	if ls.Type != nil {
+		want := c.annotation(ls.Type)
+		c.assign(ls.Value, t, want, "let "+ls.Name.Value)
+		t = want
+	}
+	if sym := c.info.Symbols.Defs[ls.Name]; sym != nil {
+		c.info.Defs[sym] = t
+	}
+}
+
+// assign reports an error if a value of type t, computed by e, cannot be used as a want
+func (c *checker) assign(e ast.Expression, t, want Type, context string) {
+	if e == nil || Assignable(t, want) {
+		return
+	}
+	c.errorf(startOf(e), "cannot use %s (type %s) as %s value in %s", e.String(), t, want, context)
+}
+
+// startOf returns the first token of e
+func startOf(e ast.Expression) *token.Token {
+	switch e := e.(type) {
+	case *ast.InfixExpression:
+		return startOf(e.Left)
+	case *ast.CallExpression:
+		return startOf(e.Function)
+	case *ast.MemberExpression:
+		return startOf(e.Object)
+	case *ast.IndexExpression:
+		return startOf(e.Left)
+	}
+
+	return ast.TokenOf(e)
+}
+
+// block checks the statements of b, returning the type of the value it ends with
+func (c *checker) block(b *ast.BlockStatement) Type {
+	if b == nil || len(b.Statements) == 0 {
+		return Null
+	}
+	for _, s := range b.Statements {
+		c.statement(s)
+	}
+	switch last := b.Statements[len(b.Statements)-1].(type) {
+	case *ast.ExpressionStatement:
+		return c.info.TypeOf(last.Expression)
+	case *ast.ReturnStatement, *ast.ThrowStatement:
+		// control never reaches the end of the block
+		return Any
+	}
+
+	return Null
+}
+
+func (c *checker) expr(e ast.Expression) Type {
+	if e == nil {
+		return Any
+	}
+	t := c.exprType(e)
+	c.info.Types[e] = t
+
+	return t
+}
+
+func (c *checker) exprType(e ast.Expression) Type {
+	switch e := e.(type) {
+	case *ast.Identifier:
+		return c.symbolType(c.info.Symbols.Uses[e])
+	case *ast.IntegerLiteral:
+		return Int
+	case *ast.StringLiteral:
+		return String
+	case *ast.Boolean:
+		return Bool
+	case *ast.Null:
+		return Null
+	case *ast.PrefixExpression:
+		return c.prefix(e, c.expr(e.Right))
+	case *ast.InfixExpression:
+		return c.infix(e, c.expr(e.Left), c.expr(e.Right))
+	case *ast.IfExpression:
+		c.expr(e.Condition)
+		consequence := c.block(e.Consequence)
+		alternative := c.block(e.Alternative)
+		if Identical(consequence, alternative) {
+			return consequence
+		}
+	case *ast.FunctionLiteral:
+		return c.function(e)
+	case *ast.MacroLiteral:
+		c.returns = append(c.returns, Any)
+		c.block(e.Body)
+		c.returns = c.returns[:len(c.returns)-1]
+	case *ast.CallExpression:
+		return c.call(e)
+	case *ast.SpreadExpression:
+		c.expr(e.Value)
+	case *ast.NamedArgument:
+		return c.expr(e.Value)
+	case *ast.MemberExpression:
+		c.expr(e.Object)
+	case *ast.IndexExpression:
+		c.expr(e.Left)
+		c.expr(e.Index)
+	case *ast.MatchExpression:
+		c.expr(e.Subject)
+		var t Type
+		for _, arm := range e.Arms {
+			c.expr(arm.Guard)
+			bt := c.block(arm.Body)
+			if t == nil {
+				t = bt
+			} else if !Identical(t, bt) {
+				t = Any
+			}
+		}
+		if t != nil {
+			return t
+		}
+	case *ast.TryExpression:
+		c.block(e.Block)
+		c.block(e.Catch)
+		c.block(e.Finally)
+	}
+
+	return Any
+}
+
+func (c *checker) prefix(e *ast.PrefixExpression, t Type) Type {
+	switch e.Operator {
+	case "!":
+		return Bool
+	case "-", "~", "++", "--":
+		if !Assignable(t, Int) {
+			c.errorf(e.Token, "invalid operation: operator %s not defined on %s (type %s)", e.Operator, e.Right.String(), t)
+		}
+		return Int
+	}
+
+	return Any
+}
+
+var (
+	arithmetic = map[string]bool{
+		"-": true, "*": true, "/": true, "%": true, "**": true,
+		"&": true, "|": true, "^": true, "<<": true, ">>": true,
+	}
+	ordered = map[string]bool{"<": true, ">": true, "<=": true, ">=": true}
+)
+
+func (c *checker) infix(e *ast.InfixExpression, l, r Type) Type {
+	switch {
+	case e.Operator == "==" || e.Operator == "!=":
+		return Bool
+	case e.Operator == "+":
+		return c.operands(e, l, r, Int, String)
+	case arithmetic[e.Operator]:
+		return c.operands(e, l, r, Int)
+	case ordered[e.Operator]:
+		c.operands(e, l, r, Int, String)
+		return Bool
+	case e.Operator == "??":
+		if Identical(l, r) {
+			return l
+		}
+	}
+
+	return Any
+}
+
+// operands checks both operands of e are of the same type, one of allowed, returning it
+func (c *checker) operands(e *ast.InfixExpression, l, r Type, allowed ...Type) Type {
+	for _, operand := range []struct {
+		e ast.Expression
+		t Type
+	}{{e.Left, l}, {e.Right, r}} {
+		if operand.t == Any {
+			continue
+		}
+		ok := false
+		for _, a := range allowed {
+			ok = ok || operand.t == a
+		}
+		if !ok {
+			c.errorf(e.Token, "invalid operation: operator %s not defined on %s (type %s)", e.Operator, operand.e.String(), operand.t)
+			return Any
+		}
+	}
+
+	switch {
+	case l == Any:
+		return r
+	case r == Any:
+		return l
+	case l != r:
+		c.errorf(e.Token, "invalid operation: %s (mismatched types %s and %s)", e.String(), l, r)
+		return Any
+	}
+
+	return l
+}
+
+func (c *checker) function(fn *ast.FunctionLiteral) Type {
+	sig := c.signature(fn)
+	for i, p := range fn.Parameters {
+		if def := fn.Default(i); def != nil {
+			c.assign(def, c.expr(def), sig.Params[i], "default of "+p.Value)
+		}
+		if sym := c.info.Symbols.Defs[p]; sym != nil {
+			c.info.Defs[sym] = sig.Params[i]
+		}
+	}
+
+	c.returns = append(c.returns, sig.Return)
+	c.block(fn.Body)
+	c.returns = c.returns[:len(c.returns)-1]
+
+	// the value of the last expression is returned
+	stmts := fn.Body.Statements
+	var last ast.Statement
+	if len(stmts) != 0 {
+		last = stmts[len(stmts)-1]
+	}
+	switch last := last.(type) {
+	case *ast.ExpressionStatement:
+		c.assign(last.Expression, c.info.TypeOf(last.Expression), sig.Return, "return")
+	case *ast.ReturnStatement, *ast.ThrowStatement:
+	default:
+		if !Assignable(Null, sig.Return) {
+			c.errorf(fn.Token, "missing return")
+		}
+	}
+
+	return sig
+}
+
+func (c *checker) call(ce *ast.CallExpression) Type {
+	ft := c.expr(ce.Function)
+	sig, isFunc := ft.(*Func)
+	if !isFunc && ft != Any {
+		c.errorf(ce.Token, "cannot call non-function %s (type %s)", ce.Function.String(), ft)
+	}
+	fn := c.literalOf(ce.Function)
+
+	checked := true // whether arguments are matched to parameters
+	for i, arg := range ce.Arguments {
+		t := c.expr(arg)
+		if !isFunc || !checked {
+			continue
+		}
+		want := Type(Any)
+		switch arg := arg.(type) {
+		case *ast.SpreadExpression:
+			// the parameters the remaining arguments go to are only known at runtime
+			checked = false
+			continue
+		case *ast.NamedArgument:
+			// names can only be matched against a function literal
+			if fn != nil {
+				for j, p := range fn.Parameters {
+					if p.Value == arg.Name.Value {
+						want = sig.Params[j]
+					}
+				}
+			}
+		default:
+			if i < len(sig.Params) {
+				want = sig.Params[i]
+			}
+		}
+		c.assign(arg, t, want, "argument to "+ce.Function.String())
+	}
+
+	if isFunc {
+		return sig.Return
+	}
+
+	return Any
+}
+
+// literalOf returns the function literal e is, or the one the name e refers to is bound to
+func (c *checker) literalOf(e ast.Expression) *ast.FunctionLiteral {
+	switch e := e.(type) {
+	case *ast.FunctionLiteral:
+		return e
+	case *ast.Identifier:
+		if sym := c.info.Symbols.Uses[e]; sym != nil {
+			fn, _ := sym.Value.(*ast.FunctionLiteral)
+			return fn
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,121 @@
+package types_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/geraldywy/monkey/ast"
+	"github.com/geraldywy/monkey/lexer"
+	"github.com/geraldywy/monkey/parser"
+	"github.com/geraldywy/monkey/types"
+)
+
+func parse(t *testing.T, input string) *ast.Program {
+	t.Helper()
+	p := parser.New(lexer.New(input, "check_test.go"))
+	program := p.ParseProgram()
+	if len(p.Errors) != 0 {
+		t.Fatalf("parser errors for %q: %v", input, p.Errors)
+	}
+
+	return program
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string // line:col message
+	}{
+		{"5 + 5; \"a\" + \"b\"; 1 < 2 == true;", []string{}},
+		{"5 + true;", []string{"1:3 invalid operation: operator + not defined on true (type bool)"}},
+		{`5 + "a";`, []string{`1:3 invalid operation: (5 + "a") (mismatched types int and string)`}},
+		{`"a" - "b";`, []string{`1:5 invalid operation: operator - not defined on "a" (type string)`}},
+		{"-true; !5;", []string{"1:1 invalid operation: operator - not defined on true (type bool)"}},
+		{"let x = 5; let y = x + true;", []string{"1:22 invalid operation: operator + not defined on true (type bool)"}},
+		{"fn(a) { a + 1; a + true };", []string{"1:18 invalid operation: operator + not defined on true (type bool)"}},
+		{`let x: int = "a";`, []string{`1:14 cannot use "a" (type string) as int value in let x`}},
+		{"let x: int = 1; let y: string = x;", []string{"1:33 cannot use x (type int) as string value in let y"}},
+		{"let x: float = 1;", []string{"1:8 unknown type float"}},
+		{"let f = fn(a: int, b: int) -> int { a + b }; f(1, true);", []string{"1:51 cannot use true (type bool) as int value in argument to f"}},
+		{"let f = fn(a: int, b: string) { a }; f(b: 1, a: 2);", []string{"1:40 cannot use b: 1 (type int) as string value in argument to f"}},
+		{"let f = fn(a: int) { a }; f(...xs, true);", []string{"1:32 undefined: xs"}},
+		{"fn() -> int { true };", []string{"1:15 cannot use true (type bool) as int value in return"}},
+		{`fn(n: int) -> string { if (n > 0) { return n; } "" };`, []string{"1:44 cannot use n (type int) as string value in return statement"}},
+		{"fn() -> int { let x = 1; };", []string{"1:1 missing return"}},
+		{"fn() -> null { let x = 1; }; fn() { let x = 1; };", []string{}},
+		{"fn(a: int = true) { a };", []string{"1:13 cannot use true (type bool) as int value in default of a"}},
+		{"let x = 5; x();", []string{"1:13 cannot call non-function x (type int)"}},
+		{"let f = fn() -> int { 1 }; let s: string = f();", []string{"1:44 cannot use f() (type int) as string value in let s"}},
+		{"let g: fn(int) -> int = fn(a: string) -> int { 1 };", []string{"1:25 cannot use fn(a: string) -> int { 1 } (type fn(string) -> int) as fn(int) -> int value in let g"}},
+		{"let g: fn(int) -> int = fn(a) { a };", []string{}},
+		{"let f = fn() { g() + 1 }; let g = fn() -> string { \"\" };", []string{"1:20 invalid operation: (g() + 1) (mismatched types string and int)"}},
+		{"let s: string = if (x) { 1 } else { 2 };", []string{"1:17 cannot use if (x) { 1 } else { 2 } (type int) as string value in let s", "1:21 undefined: x"}},
+		{"let s: string = match (1) { 1 => 1, _ => \"\" };", []string{}},
+		{"let x: any = 1; let s: string = x;", []string{}},
+	}
+	for _, tt := range tests {
+		_, errs := types.Check(parse(t, tt.input), "check_test.go")
+		got := []string{}
+		for _, err := range errs {
+			msg := strings.TrimPrefix(err.Error(), "check_test.go line: ")
+			got = append(got, strings.Replace(msg, " col: ", ":", 1))
+		}
+		if fmt.Sprint(got) != fmt.Sprint(tt.expected) {
+			t.Errorf("%q - errors wrong.\nwant=%q\ngot=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestCheckInfo(t *testing.T) {
+	program := parse(t, `let f = fn(a: int, ...xs) -> bool { a > 0 }; let b = f(1); let s = "a" + "b";`)
+	info, errs := types.Check(program, "check_test.go")
+	if len(errs) != 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"f", "fn(int, ...) -> bool"},
+		{"b", "bool"},
+		{"s", "string"},
+	}
+	global := info.Symbols.Scopes[program]
+	for _, tt := range tests {
+		sym := global.Lookup(tt.name)
+		if got := info.Defs[sym]; got == nil || got.String() != tt.expected {
+			t.Errorf("type of %s wrong. want=%s, got=%v", tt.name, tt.expected, got)
+		}
+	}
+
+	call := program.Statements[1].(*ast.LetStatement).Value
+	if info.TypeOf(call) != types.Bool {
+		t.Errorf("type of call wrong. got=%s", info.TypeOf(call))
+	}
+}
+
+func TestAssignable(t *testing.T) {
+	intToInt := &types.Func{Params: []types.Type{types.Int}, Return: types.Int}
+	anyToInt := &types.Func{Params: []types.Type{types.Any}, Return: types.Int}
+	strToInt := &types.Func{Params: []types.Type{types.String}, Return: types.Int}
+	tests := []struct {
+		v, t     types.Type
+		expected bool
+	}{
+		{types.Int, types.Int, true},
+		{types.Int, types.String, false},
+		{types.Any, types.String, true},
+		{types.Null, types.Any, true},
+		{anyToInt, intToInt, true},
+		{strToInt, intToInt, false},
+		{intToInt, types.Int, false},
+		{&types.Func{Params: []types.Type{}, Variadic: true, Return: types.Int}, &types.Func{Params: []types.Type{}, Return: types.Int}, false},
+	}
+	for _, tt := range tests {
+		if got := types.Assignable(tt.v, tt.t); got != tt.expected {
+			t.Errorf("Assignable(%s, %s) wrong. want=%t, got=%t", tt.v, tt.t, tt.expected, got)
+		}
+	}
+}
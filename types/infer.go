@@ -0,0 +1,741 @@
+package types
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/geraldywy/monkey/ast"
+	"github.com/geraldywy/monkey/resolver"
+	"github.com/geraldywy/monkey/token"
+)
+
+// Inference holds the types found by Infer
+type Inference struct {
+	Symbols  *resolver.Info
+	Types    map[ast.Expression]Type // the type of every expression inferred
+	Defs     map[*resolver.Symbol]*Scheme
+	Bindings []*Binding // the top level names, in declaration order
+}
+
+// Binding is a top level name along with its inferred type
+type Binding struct {
+	Symbol *resolver.Symbol
+	Scheme *Scheme
+}
+
+func (b *Binding) String() string { return b.Symbol.Name + ": " + b.Scheme.String() }
+
+// TypeOf returns the type inferred for e, nil if it was not inferred
+func (inf *Inference) TypeOf(e ast.Expression) Type {
+	return inf.Types[e]
+}
+
+// Infer assigns principal types to program without needing annotations, using Hindley-Milner
+// inference. Functions bound by let are polymorphic, e.g. let id = fn(x) { x }; has type
+// fn('a) -> 'a and may be called with an int and a string. Annotations, where present,
+// constrain the types inferred. Values whose type cannot be known statically, e.g. the
+// result of indexing, get a fresh type variable.
+//
+// Unlike Check, the program is typed strictly, e.g. if conditions must be bool and both
+// sides of == must have the same type. The errors returned are ordered by position, a
+// mismatch reports both of the conflicting locations.
+func Infer(program *ast.Program, fileName string, predeclared ...string) (*Inference, []error) {
+	symbols, errs := resolver.Resolve(program, fileName, predeclared...)
+	in := &inferrer{
+		fileName: fileName,
+		symbols:  symbols,
+		types:    make(map[*resolver.Symbol]*term),
+		exprs:    make(map[ast.Expression]*term),
+		errors:   errs,
+	}
+	for _, s := range program.Statements {
+		in.statement(s)
+	}
+
+	inf := &Inference{
+		Symbols: symbols,
+		Types:   make(map[ast.Expression]Type),
+		Defs:    make(map[*resolver.Symbol]*Scheme),
+	}
+	for e, t := range in.exprs {
+		inf.Types[e] = newExporter().export(t)
+	}
+	for sym, t := range in.types {
+		inf.Defs[sym] = newExporter().scheme(t)
+	}
+	if global := symbols.Scopes[program]; global != nil {
+		for _, sym := range global.Symbols {
+			if s, ok := inf.Defs[sym]; ok {
+				inf.Bindings = append(inf.Bindings, &Binding{Symbol: sym, Scheme: s})
+			}
+		}
+	}
+
+	sort.SliceStable(in.errors, func(i, j int) bool {
+		li, ci := position(in.errors[i])
+		lj, cj := position(in.errors[j])
+		if li != lj {
+			return li < lj
+		}
+		return ci < cj
+	})
+
+	return inf, in.errors
+}
+
+// genericLevel is the level of the type variables of a polymorphic type, which are replaced
+// with fresh ones each time the name is used
+const genericLevel = 1 << 30
+
+// term is a type being inferred, either a constructor applied to arguments, e.g. fn(int) -> int,
+// or a type variable
+type term struct {
+	con      string  // "int", "string", "bool", "null" or "fn", empty for a variable
+	args     []*term // the parameters then the return type of a function
+	variadic bool
+
+	// a variable is bound to ref once its type is known
+	ref   *term
+	level int      // the let nesting depth the variable was made at
+	class []string // the constructors the variable may be bound to, any if empty
+
+	origin ast.Node // where the type, or a variable's class, comes from
+}
+
+// prune returns the type t is bound to
+func prune(t *term) *term {
+	if t.con == "" && t.ref != nil {
+		t.ref = prune(t.ref)
+		return t.ref
+	}
+
+	return t
+}
+
+type inferrer struct {
+	fileName string
+	symbols  *resolver.Info
+	types    map[*resolver.Symbol]*term
+	exprs    map[ast.Expression]*term
+	level    int
+	returns  []*term // the return types of the enclosing functions, innermost last
+	errors   []error
+}
+
+func (in *inferrer) errorf(node ast.Node, format string, args ...interface{}) {
+	tkn := startOfNode(node)
+	in.errors = append(in.errors, &Error{
+		FileName: in.fileName,
+		Line:     tkn.Line,
+		Column:   tkn.Column,
+		Msg:      fmt.Sprintf(format, args...),
+	})
+}
+
+func startOfNode(node ast.Node) *token.Token {
+	if e, ok := node.(ast.Expression); ok {
+		return startOf(e)
+	}
+
+	return ast.TokenOf(node)
+}
+
+func (in *inferrer) fresh(origin ast.Node) *term {
+	return &term{level: in.level, origin: origin}
+}
+
+func con(name string, origin ast.Node) *term {
+	return &term{con: name, origin: origin}
+}
+
+func fnTerm(params []*term, ret *term, variadic bool, origin ast.Node) *term {
+	return &term{con: "fn", args: append(params, ret), variadic: variadic, origin: origin}
+}
+
+// name names t for an error, a variable by the types it may be if it is constrained
+func name(t *term) string {
+	if t.con != "" || len(t.class) == 0 {
+		return newExporter().export(t).String()
+	}
+
+	return strings.Join(t.class, " | ")
+}
+
+// describe names t for an error, along with where it comes from
+func (in *inferrer) describe(t *term) string {
+	desc := name(t)
+	if t.origin == nil {
+		return desc
+	}
+	src := t.origin.String()
+	if len(src) > 30 {
+		src = src[:27] + "..."
+	}
+	tkn := startOfNode(t.origin)
+
+	return fmt.Sprintf("%s from %s at line %d col %d", desc, src, tkn.Line, tkn.Column)
+}
+
+func (in *inferrer) mismatch(a, b *term, at ast.Node) {
+	in.errorf(at, "mismatched types %s and %s (%s, %s)", name(a), name(b), in.describe(a), in.describe(b))
+}
+
+// unify makes a and b the same type, at is the node requiring them to be
+func (in *inferrer) unify(a, b *term, at ast.Node) {
+	a, b = prune(a), prune(b)
+	switch {
+	case a == b:
+	case a.con == "":
+		in.bind(a, b, at)
+	case b.con == "":
+		in.bind(b, a, at)
+	case a.con != b.con || len(a.args) != len(b.args) || a.variadic != b.variadic:
+		in.mismatch(a, b, at)
+	default:
+		for i := range a.args {
+			in.unify(a.args[i], b.args[i], at)
+		}
+	}
+}
+
+func (in *inferrer) bind(v, t *term, at ast.Node) {
+	if t.con == "" {
+		// both are variables, t is left with the constraints of both
+		if len(v.class) != 0 {
+			if len(t.class) == 0 {
+				t.class, t.origin = v.class, v.origin
+			} else if class := intersect(v.class, t.class); len(class) != 0 {
+				t.class = class
+			} else {
+				in.mismatch(v, t, at)
+				return
+			}
+		}
+		if v.level < t.level {
+			t.level = v.level
+		}
+		v.ref = t
+		return
+	}
+
+	if len(v.class) != 0 && !contains(v.class, t.con) {
+		in.mismatch(t, v, at)
+		return
+	}
+	if in.occurs(v, t) {
+		in.errorf(at, "infinite type, %s contains itself", newExporter().export(t))
+		return
+	}
+	v.ref = t
+}
+
+// occurs reports whether v occurs in t, lowering the level of the variables of t to that
+// of v, as they are now reachable from it
+func (in *inferrer) occurs(v, t *term) bool {
+	t = prune(t)
+	if t == v {
+		return true
+	}
+	if t.con == "" {
+		if v.level < t.level {
+			t.level = v.level
+		}
+		return false
+	}
+	for _, arg := range t.args {
+		if in.occurs(v, arg) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func contains(class []string, con string) bool {
+	for _, c := range class {
+		if c == con {
+			return true
+		}
+	}
+
+	return false
+}
+
+func intersect(a, b []string) []string {
+	var out []string
+	for _, c := range a {
+		if contains(b, c) {
+			out = append(out, c)
+		}
+	}
+
+	return out
+}
+
+// generalize makes the variables of t made within the let being left polymorphic
+func (in *inferrer) generalize(t *term) {
+	t = prune(t)
+	if t.con == "" {
+		if t.level > in.level {
+			t.level = genericLevel
+		}
+		return
+	}
+	for _, arg := range t.args {
+		in.generalize(arg)
+	}
+}
+
+// instantiate replaces the polymorphic variables of t with fresh ones
+func (in *inferrer) instantiate(t *term, vars map[*term]*term) *term {
+	t = prune(t)
+	if t.con == "" {
+		if t.level != genericLevel {
+			return t
+		}
+		if v, ok := vars[t]; ok {
+			return v
+		}
+		v := &term{level: in.level, class: t.class, origin: t.origin}
+		vars[t] = v
+		return v
+	}
+	if len(t.args) == 0 {
+		return t
+	}
+	cp := *t
+	cp.args = make([]*term, len(t.args))
+	for i, arg := range t.args {
+		cp.args[i] = in.instantiate(arg, vars)
+	}
+
+	return &cp
+}
+
+// annotation returns the type an annotation refers to, any is a fresh variable
+func (in *inferrer) annotation(te ast.TypeExpr) *term {
+	switch te := te.(type) {
+	case *ast.NamedType:
+		switch te.Name {
+		case Int.Name, String.Name, Bool.Name, Null.Name:
+			return con(te.Name, te)
+		case Any.Name:
+			return in.fresh(te)
+		}
+		in.errorf(te, "unknown type %s", te.Name)
+	case *ast.FunctionType:
+		params := []*term{}
+		for _, p := range te.Parameters {
+			params = append(params, in.annotation(p))
+		}
+		return fnTerm(params, in.annotation(te.Return), false, te)
+	}
+
+	return in.fresh(te)
+}
+
+// symbol returns the type of a name as used
+func (in *inferrer) symbol(sym *resolver.Symbol, use ast.Node) *term {
+	if sym == nil || sym.Kind == resolver.Predeclared {
+		return in.fresh(use)
+	}
+	t, ok := in.types[sym]
+	if !ok {
+		// a name used before its let is inferred, e.g. by a mutually recursive function, is
+		// not polymorphic
+		t = &term{origin: sym.Decl}
+		in.types[sym] = t
+	}
+
+	return in.instantiate(t, make(map[*term]*term))
+}
+
+func (in *inferrer) statement(s ast.Statement) {
+	switch s := s.(type) {
+	case *ast.LetStatement:
+		in.let(s)
+	case *ast.ExportStatement:
+		in.let(s.Statement)
+	case *ast.ReturnStatement:
+		t := in.expr(s.ReturnValue)
+		if len(in.returns) != 0 && s.ReturnValue != nil {
+			in.unify(in.returns[len(in.returns)-1], t, s.ReturnValue)
+		}
+	case *ast.ThrowStatement:
+		in.expr(s.Value)
+	case *ast.DeferStatement:
+		in.expr(s.Call)
+	case *ast.ImportStatement:
+		if sym := in.symbols.Defs[s.Alias]; sym != nil {
+			in.types[sym] = &term{origin: s.Alias}
+		}
+	case *ast.ExpressionStatement:
+		in.expr(s.Expression)
+	case *ast.BlockStatement:
+		in.block(s)
+	}
+}
+
+func (in *inferrer) let(ls *ast.LetStatement) {
+	if ls.Pattern != nil {
+		in.expr(ls.Value)
+		for _, ident := range ast.Bindings(ls.Pattern) {
+			if sym := in.symbols.Defs[ident]; sym != nil {
+				in.types[sym] = in.fresh(ident)
+			}
+		}
+		return
+	}
+
+	sym := in.symbols.Defs[ls.Name]
+	forward, isForward := in.types[sym]
+	in.level++
+	// a function may call itself, within its body it is not polymorphic
+	self := in.fresh(ls.Name)
+	if sym != nil && !isForward {
+		in.types[sym] = self
+	}
+	t := in.expr(ls.Value)
+	if ls.Type != nil {
+		in.unify(in.annotation(ls.Type), t, ls.Value)
+	}
+	in.unify(self, t, ls.Value)
+	if isForward {
+		in.unify(forward, t, ls.Value)
+	}
+	in.level--
+
+	if sym == nil {
+		return
+	}
+	// only functions are made polymorphic, as other values may have been computed from
+	// values whose type is not yet known
+	if _, ok := ls.Value.(*ast.FunctionLiteral); ok && !isForward {
+		in.generalize(t)
+	} else {
+		// nor may an enclosing let make the variables of t polymorphic
+		in.occurs(&term{level: in.level}, t)
+	}
+	in.types[sym] = t
+}
+
+// block infers the statements of b, returning the type of the value it ends with
+func (in *inferrer) block(b *ast.BlockStatement) *term {
+	if b == nil {
+		return con("null", nil)
+	}
+	if len(b.Statements) == 0 {
+		return con("null", b)
+	}
+	for _, s := range b.Statements {
+		in.statement(s)
+	}
+	switch last := b.Statements[len(b.Statements)-1].(type) {
+	case *ast.ExpressionStatement:
+		return in.exprs[last.Expression]
+	case *ast.ReturnStatement, *ast.ThrowStatement:
+		// control never reaches the end of the block, so it may have any type
+		return in.fresh(last)
+	}
+
+	return con("null", b)
+}
+
+func (in *inferrer) expr(e ast.Expression) *term {
+	if e == nil {
+		return in.fresh(nil)
+	}
+	t := in.exprType(e)
+	in.exprs[e] = t
+
+	return t
+}
+
+func (in *inferrer) exprType(e ast.Expression) *term {
+	switch e := e.(type) {
+	case *ast.Identifier:
+		return in.symbol(in.symbols.Uses[e], e)
+	case *ast.IntegerLiteral:
+		return con("int", e)
+	case *ast.StringLiteral:
+		return con("string", e)
+	case *ast.Boolean:
+		return con("bool", e)
+	case *ast.Null:
+		return con("null", e)
+	case *ast.PrefixExpression:
+		return in.prefix(e)
+	case *ast.InfixExpression:
+		return in.infix(e)
+	case *ast.IfExpression:
+		in.unify(con("bool", e), in.expr(e.Condition), e.Condition)
+		consequence := in.block(e.Consequence)
+		if e.Alternative == nil {
+			// the value is null when the condition is false
+			return con("null", e)
+		}
+		in.unify(consequence, in.block(e.Alternative), e)
+		return consequence
+	case *ast.FunctionLiteral:
+		return in.function(e)
+	case *ast.MacroLiteral:
+		for _, p := range e.Parameters {
+			if sym := in.symbols.Defs[p]; sym != nil {
+				in.types[sym] = in.fresh(p)
+			}
+		}
+		in.returns = append(in.returns, in.fresh(e))
+		in.block(e.Body)
+		in.returns = in.returns[:len(in.returns)-1]
+	case *ast.CallExpression:
+		return in.call(e)
+	case *ast.SpreadExpression:
+		in.expr(e.Value)
+	case *ast.NamedArgument:
+		return in.expr(e.Value)
+	case *ast.MemberExpression:
+		in.expr(e.Object)
+	case *ast.IndexExpression:
+		in.expr(e.Left)
+		in.expr(e.Index)
+	case *ast.MatchExpression:
+		return in.match(e)
+	case *ast.TryExpression:
+		t := in.block(e.Block)
+		if e.Catch != nil {
+			if sym := in.symbols.Defs[e.CatchParam]; sym != nil {
+				in.types[sym] = in.fresh(e.CatchParam)
+			}
+			in.unify(t, in.block(e.Catch), e.Catch)
+		}
+		in.block(e.Finally)
+		return t
+	}
+
+	return in.fresh(e)
+}
+
+func (in *inferrer) prefix(e *ast.PrefixExpression) *term {
+	right := in.expr(e.Right)
+	switch e.Operator {
+	case "!":
+		return con("bool", e)
+	case "-", "~", "++", "--":
+		in.unify(con("int", e), right, e.Right)
+		return con("int", e)
+	}
+
+	return in.fresh(e)
+}
+
+func (in *inferrer) infix(e *ast.InfixExpression) *term {
+	l, r := in.expr(e.Left), in.expr(e.Right)
+	switch {
+	case e.Operator == "==" || e.Operator == "!=":
+		in.unify(l, r, e)
+		return con("bool", e)
+	case e.Operator == "+":
+		in.unify(l, r, e)
+		in.unify(&term{level: in.level, class: []string{"int", "string"}, origin: e}, l, e)
+		return l
+	case arithmetic[e.Operator]:
+		in.unify(con("int", e), l, e.Left)
+		in.unify(con("int", e), r, e.Right)
+		return con("int", e)
+	case ordered[e.Operator]:
+		in.unify(l, r, e)
+		in.unify(&term{level: in.level, class: []string{"int", "string"}, origin: e}, l, e)
+		return con("bool", e)
+	case e.Operator == "??":
+		in.unify(l, r, e)
+		return l
+	}
+
+	return in.fresh(e)
+}
+
+func (in *inferrer) function(fn *ast.FunctionLiteral) *term {
+	params := []*term{}
+	for i, p := range fn.Parameters {
+		var t *term
+		if a := fn.ParamType(i); a != nil {
+			t = in.annotation(a)
+		} else {
+			t = in.fresh(p)
+		}
+		if def := fn.Default(i); def != nil {
+			in.unify(t, in.expr(def), def)
+		}
+		if sym := in.symbols.Defs[p]; sym != nil {
+			in.types[sym] = t
+		}
+		params = append(params, t)
+	}
+	if fn.Rest != nil {
+		if sym := in.symbols.Defs[fn.Rest]; sym != nil {
+			in.types[sym] = in.fresh(fn.Rest)
+		}
+	}
+
+	ret := in.fresh(fn)
+	if fn.ReturnType != nil {
+		ret = in.annotation(fn.ReturnType)
+	}
+	in.returns = append(in.returns, ret)
+	t := in.block(fn.Body)
+	in.returns = in.returns[:len(in.returns)-1]
+
+	// the value of the last statement is returned
+	var at ast.Node = fn
+	if n := len(fn.Body.Statements); n != 0 {
+		at = fn.Body.Statements[n-1]
+		if es, ok := at.(*ast.ExpressionStatement); ok {
+			at = es.Expression
+		}
+	}
+	in.unify(ret, t, at)
+
+	return fnTerm(params, ret, fn.Rest != nil, fn)
+}
+
+func (in *inferrer) call(ce *ast.CallExpression) *term {
+	callee := prune(in.expr(ce.Function))
+	args := []*term{}
+	positional := true
+	for _, arg := range ce.Arguments {
+		args = append(args, in.expr(arg))
+		switch arg.(type) {
+		case *ast.SpreadExpression, *ast.NamedArgument:
+			// the parameters these go to are only known at runtime, or by name
+			positional = false
+		}
+	}
+
+	switch {
+	case callee.con == "fn":
+		if positional {
+			min, max := len(callee.args)-1, len(callee.args)-1
+			if callee.variadic {
+				max = -1
+			}
+			// the parameters of a literal with defaults may be left out
+			if fn, ok := callee.origin.(*ast.FunctionLiteral); ok {
+				min, max = fn.Arity()
+			}
+			switch {
+			case len(args) < min:
+				in.errorf(ce, "not enough arguments in call to %s, got %d want %d", ce.Function.String(), len(args), min)
+			case max != -1 && len(args) > max:
+				in.errorf(ce, "too many arguments in call to %s, got %d want %d", ce.Function.String(), len(args), max)
+			}
+			for i, arg := range args {
+				if i < len(callee.args)-1 {
+					in.unify(callee.args[i], arg, ce.Arguments[i])
+				}
+			}
+		}
+		return callee.args[len(callee.args)-1]
+	case callee.con == "":
+		if !positional {
+			return in.fresh(ce)
+		}
+		ret := in.fresh(ce)
+		in.unify(callee, fnTerm(args, ret, false, ce), ce)
+		return ret
+	}
+	in.errorf(ce, "cannot call non-function %s (%s)", ce.Function.String(), in.describe(callee))
+
+	return in.fresh(ce)
+}
+
+func (in *inferrer) match(me *ast.MatchExpression) *term {
+	subject := in.expr(me.Subject)
+	var t *term
+	for _, arm := range me.Arms {
+		in.pattern(arm.Pattern, subject)
+		if arm.Guard != nil {
+			in.unify(con("bool", arm.Guard), in.expr(arm.Guard), arm.Guard)
+		}
+		bt := in.block(arm.Body)
+		if t == nil {
+			t = bt
+		} else {
+			in.unify(t, bt, arm.Body)
+		}
+	}
+	if t == nil {
+		return con("null", me)
+	}
+
+	return t
+}
+
+// pattern constrains subject by the pattern matched against it, typing the names bound
+func (in *inferrer) pattern(p ast.Pattern, subject *term) {
+	switch p := p.(type) {
+	case *ast.IdentifierPattern:
+		if sym := in.symbols.Defs[p.Name]; sym != nil {
+			in.types[sym] = subject
+		}
+	case *ast.LiteralPattern:
+		in.unify(subject, in.expr(p.Value), p)
+	default:
+		// the elements of arrays and hashes are not typed
+		for _, ident := range ast.Bindings(p) {
+			if sym := in.symbols.Defs[ident]; sym != nil {
+				in.types[sym] = in.fresh(ident)
+			}
+		}
+	}
+}
+
+// exporter turns terms into Types, naming their variables 'a, 'b, ... in order of appearance
+type exporter struct {
+	vars  map[*term]*Var
+	order []*Var
+}
+
+func newExporter() *exporter {
+	return &exporter{vars: make(map[*term]*Var)}
+}
+
+func (ex *exporter) export(t *term) Type {
+	t = prune(t)
+	switch t.con {
+	case "":
+		if v, ok := ex.vars[t]; ok {
+			return v
+		}
+		v := &Var{Name: varName(len(ex.order))}
+		for _, c := range t.class {
+			v.Class = append(v.Class, basics[c])
+		}
+		ex.vars[t] = v
+		ex.order = append(ex.order, v)
+		return v
+	case "fn":
+		f := &Func{Params: []Type{}, Variadic: t.variadic}
+		for _, p := range t.args[:len(t.args)-1] {
+			f.Params = append(f.Params, ex.export(p))
+		}
+		f.Return = ex.export(t.args[len(t.args)-1])
+		return f
+	}
+
+	return basics[t.con]
+}
+
+func (ex *exporter) scheme(t *term) *Scheme {
+	return &Scheme{Type: ex.export(t), Vars: ex.order}
+}
+
+func varName(i int) string {
+	name := string(rune('a' + i%26))
+	if i >= 26 {
+		name += strconv.Itoa(i / 26)
+	}
+
+	return name
+}
@@ -0,0 +1,106 @@
+package types_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/geraldywy/monkey/ast"
+	"github.com/geraldywy/monkey/types"
+)
+
+func TestInferBindings(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"let x = 5; let b = !x; let s = \"a\" + \"b\";", []string{"x: int", "b: bool", "s: string"}},
+		{"let id = fn(x) { x };", []string{"id: fn('a) -> 'a"}},
+		{"let id = fn(x) { x }; let a = id(1); let b = id(true);", []string{"id: fn('a) -> 'a", "a: int", "b: bool"}},
+		{"let k = fn(x, y) { x };", []string{"k: fn('a, 'b) -> 'a"}},
+		{"let add = fn(a, b) { a + b };", []string{"add: fn('a, 'a) -> 'a where 'a: int | string"}},
+		{"let inc = fn(a) { a + 1 };", []string{"inc: fn(int) -> int"}},
+		{"let max = fn(a, b) { if (a > b) { a } else { b } };", []string{"max: fn('a, 'a) -> 'a where 'a: int | string"}},
+		{"let apply = fn(f, x) { f(x) };", []string{"apply: fn(fn('a) -> 'b, 'a) -> 'b"}},
+		{"let compose = fn(f, g) { fn(x) { f(g(x)) } };", []string{"compose: fn(fn('a) -> 'b, fn('c) -> 'a) -> fn('c) -> 'b"}},
+		{"let fact = fn(n) { if (n < 2) { return 1; } n * fact(n - 1) };", []string{"fact: fn(int) -> int"}},
+		{"let even = fn(n) { if (n == 0) { true } else { odd(n - 1) } }; let odd = fn(n) { if (n == 0) { false } else { even(n - 1) } };", []string{"even: fn(int) -> bool", "odd: fn(int) -> bool"}},
+		{"let f = fn(x: string, ...rest) { x };", []string{"f: fn(string, ...) -> string"}},
+		{"let f = fn(x = 1) { x };", []string{"f: fn(int) -> int"}},
+		{"let f = fn(x) -> int { x };", []string{"f: fn(int) -> int"}},
+		{"let f = fn(x) { match (x) { 0 => \"zero\", n => \"many\" } };", []string{"f: fn(int) -> string"}},
+		{"let f = fn(x) { let y = x; y };", []string{"f: fn('a) -> 'a"}},
+		{"let n = null; let f = fn() {};", []string{"n: null", "f: fn() -> null"}},
+		{"let f = fn(h) { h.x };", []string{"f: fn('a) -> 'b"}},
+	}
+	for _, tt := range tests {
+		inf, errs := types.Infer(parse(t, tt.input), "infer_test.go")
+		if len(errs) != 0 {
+			t.Errorf("%q - unexpected errors: %v", tt.input, errs)
+			continue
+		}
+		got := []string{}
+		for _, b := range inf.Bindings {
+			got = append(got, b.String())
+		}
+		if fmt.Sprint(got) != fmt.Sprint(tt.expected) {
+			t.Errorf("%q - bindings wrong.\nwant=%q\ngot=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestInferErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string // line:col message
+	}{
+		{"5 + true;", []string{"1:1 mismatched types int and bool (int from 5 at line 1 col 1, bool from true at line 1 col 5)"}},
+		{"let f = fn(x) { x + 1 };\nf(true);", []string{"2:3 mismatched types int and bool (int from 1 at line 1 col 21, bool from true at line 2 col 3)"}},
+		{"if (1) { 2 } else { 3 };", []string{"1:5 mismatched types bool and int (bool from if (1) { 2 } else { 3 } at line 1 col 1, int from 1 at line 1 col 5)"}},
+		{"if (true) { 1 } else { \"a\" };", []string{"1:1 mismatched types int and string (int from 1 at line 1 col 13, string from \"a\" at line 1 col 24)"}},
+		{"true + false;", []string{"1:1 mismatched types bool and int | string (bool from true at line 1 col 1, int | string from (true + false) at line 1 col 1)"}},
+		{"let x = 5; x();", []string{"1:12 cannot call non-function x (int from 5 at line 1 col 9)"}},
+		{"let f = fn(x) { x(x) };", []string{"1:17 infinite type, fn('a) -> 'b contains itself"}},
+		{"let f = fn(x: int) { x }; f(\"a\");", []string{"1:29 mismatched types int and string (int from int at line 1 col 15, string from \"a\" at line 1 col 29)"}},
+		{"let s: string = 1;", []string{"1:17 mismatched types string and int (string from string at line 1 col 8, int from 1 at line 1 col 17)"}},
+		{"let f = fn(x) { if (x) { return 1; } \"a\" };", []string{"1:38 mismatched types int and string (int from 1 at line 1 col 33, string from \"a\" at line 1 col 38)"}},
+		{"y + 1;", []string{"1:1 undefined: y"}},
+		{"let g = fn(a, b) { a }; g(1, 2, 3);", []string{"1:25 too many arguments in call to g, got 3 want 2"}},
+		{"let g = fn(a, b) { a }; g(1);", []string{"1:25 not enough arguments in call to g, got 1 want 2"}},
+		{"let g = fn(a, b = 1, ...c) { a }; g(1); g(1, 2, 3, 4); g();", []string{"1:56 not enough arguments in call to g, got 0 want 1"}},
+		{"let g: fn(int) -> int = fn(a) { a }; g(1, 2);", []string{"1:38 too many arguments in call to g, got 2 want 1"}},
+		{"let h = fn(f) { f(1); f(1, 2) };", []string{"1:23 too many arguments in call to f, got 2 want 1"}},
+	}
+	for _, tt := range tests {
+		_, errs := types.Infer(parse(t, tt.input), "infer_test.go")
+		got := []string{}
+		for _, err := range errs {
+			msg := strings.TrimPrefix(err.Error(), "infer_test.go line: ")
+			got = append(got, strings.Replace(msg, " col: ", ":", 1))
+		}
+		if fmt.Sprint(got) != fmt.Sprint(tt.expected) {
+			t.Errorf("%q - errors wrong.\nwant=%q\ngot=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestInferTypes(t *testing.T) {
+	program := parse(t, "let id = fn(x) { x }; id(1) + 2; id;")
+	inf, errs := types.Infer(program, "infer_test.go")
+	if len(errs) != 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+
+	tests := []struct {
+		expr     ast.Expression
+		expected string
+	}{
+		{program.Statements[1].(*ast.ExpressionStatement).Expression, "int"},
+		{program.Statements[2].(*ast.ExpressionStatement).Expression, "fn('a) -> 'a"},
+	}
+	for _, tt := range tests {
+		if got := inf.TypeOf(tt.expr); got == nil || got.String() != tt.expected {
+			t.Errorf("type of %s wrong. want=%s, got=%v", tt.expr, tt.expected, got)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+// Package types checks Monkey programs against their optional type annotations, e.g.
+// let x: int = 5; or fn(a: int) -> int { a }, reporting the type errors found.
+package types
+
+import (
+	"bytes"
+	"strings"
+)
+
+// Type is the static type of a value
+type Type interface {
+	String() string
+}
+
+// Basic is a type referred to by name
+type Basic struct {
+	Name string
+}
+
+func (b *Basic) String() string { return b.Name }
+
+var (
+	Int    = &Basic{"int"}
+	String = &Basic{"string"}
+	Bool   = &Basic{"bool"}
+	Null   = &Basic{"null"}
+	// Any is the type of a value that is not checked, e.g. an unannotated parameter. It is
+	// assignable to and from every type.
+	Any = &Basic{"any"}
+)
+
+// basics maps the names usable in annotations to their type
+var basics = map[string]Type{
+	Int.Name:    Int,
+	String.Name: String,
+	Bool.Name:   Bool,
+	Null.Name:   Null,
+	Any.Name:    Any,
+}
+
+// Func is the type of a function, variadic functions take any number of extra arguments
+type Func struct {
+	Params   []Type
+	Variadic bool
+	Return   Type
+}
+
+func (f *Func) String() string {
+	var out bytes.Buffer
+	params := []string{}
+	for _, p := range f.Params {
+		params = append(params, p.String())
+	}
+	if f.Variadic {
+		params = append(params, "...")
+	}
+	out.WriteString("fn(")
+	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(") -> ")
+	out.WriteString(f.Return.String())
+	return out.String()
+}
+
+// Var is a type variable, standing for any type, or any of Class if it is not empty
+type Var struct {
+	Name  string
+	Class []Type
+}
+
+func (v *Var) String() string { return "'" + v.Name }
+
+// Scheme is the type of a let bound name, it is polymorphic in its type variables, e.g.
+// fn('a) -> 'a for fn(x) { x }
+type Scheme struct {
+	Vars []*Var // in order of appearance
+	Type Type
+}
+
+func (s *Scheme) String() string {
+	var out bytes.Buffer
+	out.WriteString(s.Type.String())
+	constraints := []string{}
+	for _, v := range s.Vars {
+		if len(v.Class) == 0 {
+			continue
+		}
+		class := []string{}
+		for _, t := range v.Class {
+			class = append(class, t.String())
+		}
+		constraints = append(constraints, v.String()+": "+strings.Join(class, " | "))
+	}
+	if len(constraints) != 0 {
+		out.WriteString(" where ")
+		out.WriteString(strings.Join(constraints, ", "))
+	}
+	return out.String()
+}
+
+// Assignable reports whether a value of type v may be used where t is expected
+func Assignable(v, t Type) bool {
+	if v == Any || t == Any {
+		return true
+	}
+	vf, ok := v.(*Func)
+	tf, ok2 := t.(*Func)
+	if !ok || !ok2 {
+		return v == t
+	}
+	if len(vf.Params) != len(tf.Params) || vf.Variadic != tf.Variadic {
+		return false
+	}
+	// a function may be used where one taking narrower arguments is expected
+	for i := range vf.Params {
+		if !Assignable(tf.Params[i], vf.Params[i]) {
+			return false
+		}
+	}
+
+	return Assignable(vf.Return, tf.Return)
+}
+
+// Identical reports whether a and b are the same type
+func Identical(a, b Type) bool {
+	af, ok := a.(*Func)
+	bf, ok2 := b.(*Func)
+	if !ok || !ok2 {
+		return a == b
+	}
+	if len(af.Params) != len(bf.Params) || af.Variadic != bf.Variadic {
+		return false
+	}
+	for i := range af.Params {
+		if !Identical(af.Params[i], bf.Params[i]) {
+			return false
+		}
+	}
+
+	return Identical(af.Return, bf.Return)
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/geraldywy/monkey/vet"
+)
+
+func vetCmd(args []string) int {
+	fs := flag.NewFlagSet("vet", flag.ExitOnError)
+	rules := fs.String("rules", "", "comma separated rules to run, all of them if empty")
+	list := fs.Bool("list", false, "list the available rules")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: monkey vet [-rules rule,...] <file>...")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if *list {
+		for _, r := range vet.Rules {
+			fmt.Printf("%-20s %s\n", r.ID, r.Doc)
+		}
+		return 0
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+	var enabled []string
+	if *rules != "" {
+		enabled = strings.Split(*rules, ",")
+	}
+
+	code := 0
+	for _, path := range fs.Args() {
+		program, ok := parseFile(path)
+		if !ok {
+			code = 1
+			continue
+		}
+		diags, err := vet.Check(program, path, enabled...)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		for _, d := range diags {
+			fmt.Fprintln(os.Stderr, d)
+			code = 1
+		}
+	}
+
+	return code
+}
@@ -0,0 +1,263 @@
+// Package vet reports suspicious constructs in Monkey programs that parse, but are likely
+// mistakes, e.g. a binding that is never used or code that can never run.
+package vet
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/geraldywy/monkey/ast"
+	"github.com/geraldywy/monkey/resolver"
+	"github.com/geraldywy/monkey/token"
+)
+
+const (
+	UnusedLet         = "unused-let"
+	UnusedParam       = "unused-param"
+	Shadow            = "shadow"
+	Unreachable       = "unreachable"
+	SelfCompare       = "self-compare"
+	ConstantCondition = "constant-condition"
+	ArgCount          = "arg-count"
+)
+
+type Rule struct {
+	ID  string
+	Doc string
+}
+
+// Rules lists every rule, all of them are enabled by default
+var Rules = []*Rule{
+	{UnusedLet, "a let binding that is never used"},
+	{UnusedParam, "a function parameter that is never used"},
+	{Shadow, "a declaration hiding one of the same name in an enclosing scope"},
+	{Unreachable, "a statement following a return or throw in the same block"},
+	{SelfCompare, "a value compared to itself"},
+	{ConstantCondition, "an if whose condition does not depend on anything"},
+	{ArgCount, "a call to a known function with arguments it does not accept"},
+}
+
+// Diagnostic is a single problem found by a rule
+type Diagnostic struct {
+	Rule     string
+	FileName string
+	Line     int
+	Column   int
+	Message  string
+}
+
+func (d *Diagnostic) String() string {
+	return fmt.Sprintf("%s line: %d col: %d %s [%s]", d.FileName, d.Line, d.Column, d.Message, d.Rule)
+}
+
+// Check runs the given rules over program, or every rule if rules is empty, returning the
+// diagnostics ordered by position. Bindings whose names start with '_' are never reported
+// as unused.
+func Check(program *ast.Program, fileName string, rules ...string) ([]*Diagnostic, error) {
+	c := &checker{fileName: fileName, enabled: make(map[string]bool)}
+	for _, r := range Rules {
+		c.enabled[r.ID] = len(rules) == 0
+	}
+	for _, r := range rules {
+		if _, ok := c.enabled[r]; !ok {
+			return nil, errors.New(fmt.Sprintf("unknown rule %q", r))
+		}
+		c.enabled[r] = true
+	}
+
+	// names that do not resolve are errors rather than likely mistakes, so they are not
+	// reported here
+	c.info, _ = resolver.Resolve(program, fileName)
+	c.symbols(c.info.Universe)
+	ast.Inspect(program, c.node)
+
+	sort.SliceStable(c.diags, func(i, j int) bool {
+		a, b := c.diags[i], c.diags[j]
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+
+	return c.diags, nil
+}
+
+type checker struct {
+	fileName string
+	enabled  map[string]bool
+	info     *resolver.Info
+	diags    []*Diagnostic
+}
+
+func (c *checker) report(rule string, tkn *token.Token, format string, args ...interface{}) {
+	if !c.enabled[rule] || tkn == nil {
+		return
+	}
+	c.diags = append(c.diags, &Diagnostic{
+		Rule:     rule,
+		FileName: c.fileName,
+		Line:     tkn.Line,
+		Column:   tkn.Column,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// symbols reports the unused and shadowing declarations of s and the scopes within it
+func (c *checker) symbols(s *resolver.Scope) {
+	for _, sym := range s.Symbols {
+		if sym.Decl == nil {
+			continue
+		}
+		if outer := sym.Shadows; outer != nil && outer.Decl != nil {
+			c.report(Shadow, sym.Decl.Token, "declaration of %s shadows the one at line %d col %d",
+				sym.Name, outer.Decl.Token.Line, outer.Decl.Token.Column)
+		}
+		if sym.Name == "" || sym.Name[0] == '_' || used(sym) {
+			continue
+		}
+		switch {
+		case sym.Kind == resolver.Let && !sym.Exported:
+			c.report(UnusedLet, sym.Decl.Token, "%s declared but not used", sym.Name)
+		case sym.Kind == resolver.Param:
+			c.report(UnusedParam, sym.Decl.Token, "parameter %s is not used", sym.Name)
+		}
+	}
+	for _, child := range s.Children {
+		c.symbols(child)
+	}
+}
+
+// used reports whether sym is used, other than by the function it is bound to calling itself
+func used(sym *resolver.Symbol) bool {
+	fn, ok := sym.Value.(*ast.FunctionLiteral)
+	if !ok || len(sym.Uses) == 0 {
+		return len(sym.Uses) != 0
+	}
+	within := make(map[*ast.Identifier]bool)
+	ast.Inspect(fn, func(node ast.Node) bool {
+		if ident, ok := node.(*ast.Identifier); ok {
+			within[ident] = true
+		}
+		return true
+	})
+	for _, use := range sym.Uses {
+		if !within[use] {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c *checker) node(node ast.Node) bool {
+	switch n := node.(type) {
+	case *ast.Program:
+		c.statements(n.Statements)
+	case *ast.BlockStatement:
+		c.statements(n.Statements)
+	case *ast.InfixExpression:
+		c.checkSelfCompare(n)
+	case *ast.IfExpression:
+		if isConstant(n.Condition) {
+			c.report(ConstantCondition, n.Token, "condition %s is constant", n.Condition.String())
+		}
+	case *ast.CallExpression:
+		c.checkArgCount(n)
+	}
+
+	return true
+}
+
+func (c *checker) statements(stmts []ast.Statement) {
+	terminated := false
+	for _, s := range stmts {
+		if terminated {
+			c.report(Unreachable, ast.TokenOf(s), "unreachable code")
+			// only the first unreachable statement is reported
+			break
+		}
+		switch s.(type) {
+		case *ast.ReturnStatement, *ast.ThrowStatement:
+			terminated = true
+		}
+	}
+}
+
+var comparisons = map[string]bool{"==": true, "!=": true, "<": true, ">": true}
+
+func (c *checker) checkSelfCompare(ie *ast.InfixExpression) {
+	if comparisons[ie.Operator] && isPure(ie.Left) && ast.Equal(ie.Left, ie.Right) {
+		c.report(SelfCompare, ie.Token, "%s is compared to itself", ie.Left.String())
+	}
+}
+
+func (c *checker) checkArgCount(ce *ast.CallExpression) {
+	var fn *ast.FunctionLiteral
+	name := "function"
+	switch callee := ce.Function.(type) {
+	case *ast.FunctionLiteral:
+		fn = callee
+	case *ast.Identifier:
+		if sym := c.info.Uses[callee]; sym != nil {
+			fn, _ = sym.Value.(*ast.FunctionLiteral)
+			name = callee.Value
+		}
+	}
+	if fn == nil {
+		return
+	}
+
+	positional := []struct{}{}
+	named := []ast.NamedValue[struct{}]{}
+	for _, arg := range ce.Arguments {
+		switch arg := arg.(type) {
+		case *ast.SpreadExpression:
+			// the number of arguments is only known at runtime
+			return
+		case *ast.NamedArgument:
+			named = append(named, ast.NamedValue[struct{}]{Name: arg.Name.Value})
+		default:
+			positional = append(positional, struct{}{})
+		}
+	}
+	if _, _, _, err := ast.BindArguments(fn.CallSignature(name), positional, named); err != nil {
+		c.report(ArgCount, ce.Token, "%s", err)
+	}
+}
+
+// isConstant reports whether e is made up of literals only
+func isConstant(e ast.Expression) bool {
+	switch e := e.(type) {
+	case *ast.IntegerLiteral, *ast.StringLiteral, *ast.Boolean, *ast.Null:
+		return true
+	case *ast.PrefixExpression:
+		return isConstant(e.Right)
+	case *ast.InfixExpression:
+		return isConstant(e.Left) && isConstant(e.Right)
+	}
+
+	return false
+}
+
+// isPure reports whether evaluating e twice gives the same value, e.g. it makes no calls
+func isPure(e ast.Expression) bool {
+	switch e := e.(type) {
+	case *ast.Identifier, *ast.IntegerLiteral, *ast.StringLiteral, *ast.Boolean, *ast.Null:
+		return true
+	case *ast.PrefixExpression:
+		// ++ and -- change their operand
+		if e.Operator == "++" || e.Operator == "--" {
+			return false
+		}
+		return isPure(e.Right)
+	case *ast.InfixExpression:
+		return isPure(e.Left) && isPure(e.Right)
+	case *ast.MemberExpression:
+		return isPure(e.Object)
+	case *ast.IndexExpression:
+		return isPure(e.Left) && isPure(e.Index)
+	}
+
+	return false
+}
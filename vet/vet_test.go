@@ -0,0 +1,87 @@
+package vet_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/geraldywy/monkey/lexer"
+	"github.com/geraldywy/monkey/parser"
+	"github.com/geraldywy/monkey/vet"
+)
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		rule     string
+		expected []string // line:col rule
+	}{
+		{"unused let", "let x = 1; let y = 2; y;", vet.UnusedLet, []string{"1:5 unused-let"}},
+		{"underscore let", "let _x = 1; let [_, b] = xs; b;", vet.UnusedLet, []string{}},
+		{"unused destructured let", "let [a, b] = xs; a;", vet.UnusedLet, []string{"1:9 unused-let"}},
+		{"used in later function", "let f = fn() { g() }; let g = fn() { f() }; f();", vet.UnusedLet, []string{}},
+		{"recursive function", "let f = fn(n) { f(n - 1) };", vet.UnusedLet, []string{"1:5 unused-let"}},
+		{"exported let", "export let x = 1;", vet.UnusedLet, []string{}},
+		{"unused param", "let f = fn(a, b, ...c) { a }; f(1, 2);", vet.UnusedParam, []string{"1:15 unused-param", "1:21 unused-param"}},
+		{"param used by default", "let f = fn(a, b = a) { b }; f(1);", vet.UnusedParam, []string{}},
+		{"unused arrow param", "map(xs, (x, i) => x);", vet.UnusedParam, []string{"1:13 unused-param"}},
+		{"shadowed let", "let x = 1; let f = fn() { let x = 2; x }; f(x);", vet.Shadow, []string{"1:31 shadow"}},
+		{"shadowed param", "let x = 1; let f = fn(x) { x }; f(x);", vet.Shadow, []string{"1:23 shadow"}},
+		{"redeclared let", "let x = 1; let x = x + 1; x;", vet.Shadow, []string{}},
+		{"shadowing match binding", "let n = 1; match (n) { n => n };", vet.Shadow, []string{"1:24 shadow"}},
+		{"after return", "fn() { return 1; let x = 2; x; }", vet.Unreachable, []string{"1:18 unreachable"}},
+		{"after throw", "fn() { if (x) { throw 1; y; } z }", vet.Unreachable, []string{"1:26 unreachable"}},
+		{"nothing unreachable", "fn() { if (x) { return 1; } z }", vet.Unreachable, []string{}},
+		{"self compare", "x == x; a.b != a.b; f() == f(); x == y;", vet.SelfCompare, []string{"1:3 self-compare", "1:13 self-compare"}},
+		{"increment compared", "++y == ++y; --y == --y; -y == -y;", vet.SelfCompare, []string{"1:28 self-compare"}},
+		{"constant condition", "if (true) { 1 }; if (1 < 2) { 1 }; if (x) { 1 }", vet.ConstantCondition, []string{"1:1 constant-condition", "1:18 constant-condition"}},
+		{"wrong argument count", "let f = fn(a, b = 1) { a + b }; f(); f(1); f(1, 2, 3); f(...xs);", vet.ArgCount, []string{"1:34 arg-count", "1:45 arg-count"}},
+		{"unknown named argument", "let f = fn(a) { a }; f(a: 1); f(b: 1);", vet.ArgCount, []string{"1:32 arg-count"}},
+		{"immediately called", "fn(a) { a }();", vet.ArgCount, []string{"1:12 arg-count"}},
+		{"shadowed function", "let f = fn(a) { a }; let g = fn(f) { f() }; g(f);", vet.ArgCount, []string{}},
+	}
+	for _, tt := range tests {
+		p := parser.New(lexer.New(tt.input, "vet_test.go"))
+		program := p.ParseProgram()
+		if len(p.Errors) != 0 {
+			t.Fatalf("%s - parser errors: %v", tt.name, p.Errors)
+		}
+		diags, err := vet.Check(program, "vet_test.go", tt.rule)
+		if err != nil {
+			t.Fatalf("%s - unexpected error: %s", tt.name, err)
+		}
+		got := []string{}
+		for _, d := range diags {
+			got = append(got, fmt.Sprintf("%d:%d %s", d.Line, d.Column, d.Rule))
+		}
+		if fmt.Sprint(got) != fmt.Sprint(tt.expected) {
+			t.Errorf("%s - diagnostics wrong. want=%v, got=%v", tt.name, tt.expected, got)
+		}
+	}
+}
+
+func TestCheckAllRules(t *testing.T) {
+	p := parser.New(lexer.New("let f = fn(x) { return 1; x == x };", "vet_test.go"))
+	program := p.ParseProgram()
+	diags, err := vet.Check(program, "vet_test.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"vet_test.go line: 1 col: 5 f declared but not used [unused-let]",
+		"vet_test.go line: 1 col: 27 unreachable code [unreachable]",
+		"vet_test.go line: 1 col: 29 x is compared to itself [self-compare]",
+	}
+	if len(diags) != len(want) {
+		t.Fatalf("wrong number of diagnostics. want=%d, got=%v", len(want), diags)
+	}
+	for i, d := range diags {
+		if d.String() != want[i] {
+			t.Errorf("diagnostic %d wrong. want=%q, got=%q", i, want[i], d.String())
+		}
+	}
+
+	if _, err := vet.Check(program, "vet_test.go", "no-such-rule"); err == nil {
+		t.Errorf("expected an error for an unknown rule")
+	}
+}